@@ -8,9 +8,21 @@ import (
 
 const (
 	// CommandFilterKey should be used as a key to in a cobra.Annotation map.
-	// The value will be used as a filter to disable commands when the console
-	// calls the Filter("name") method on the console.
-	// The string value will be comma-splitted, with each split being a filter.
+	// The value is a filter expression hiding the command when it is true, a
+	// filter name being true when it is activated with Console.HideCommands.
+	//
+	// Filter names can be combined with `!` (not), `&&` or `&` (and), `||`, `|`
+	// or `,` (or) and parentheses. A comma-separated list of filters thus hides
+	// the command as soon as one of them is active, `!admin` hides the command
+	// unless the admin filter is active, and `windows && !beacon` hides it when
+	// windows is active, unless beacon is too.
+	//
+	// Flags can be filtered the same way, with a flag annotation:
+	//
+	//	cmd.Flags().SetAnnotation("registry", console.CommandFilterKey, []string{"!windows"})
+	//
+	// Filtered flags are hidden from help and completions, and commands
+	// using them are refused at execution, like filtered commands are.
 	CommandFilterKey = command.FilterKey
 )
 
@@ -27,26 +39,27 @@ func (m *Menu) SetCommands(cmds Commands) {
 	m.cmds = cmds
 }
 
+// hasGenerator returns true if the menu has a commands function,
+// generating new command trees instead of reusing its bound one.
+func (m *Menu) hasGenerator() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.cmds != nil
+}
+
 // HideCommands - Commands, in addition to their menus, can be shown/hidden based
 // on a filter string. For example, some commands applying to a Windows host might
 // be scattered around different groups, but, having all the filter "windows".
 // If "windows" is used as the argument here, all windows commands for the current
 // menu are subsequently hidden, until ShowCommands("windows") is called.
+// Note that activating a filter shows the commands whose filter expression
+// negates it, like "!windows" (see CommandFilterKey).
 func (c *Console) HideCommands(filters ...string) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-next:
-	for _, filt := range filters {
-		for _, filter := range c.filters {
-			if filt == filter {
-				continue next
-			}
-		}
-		if filt != "" {
-			c.filters = append(c.filters, filt)
-		}
-	}
+	c.filters = addFilters(c.filters, filters...)
 }
 
 // ShowCommands - Commands, in addition to their menus, can be shown/hidden based
@@ -58,16 +71,83 @@ func (c *Console) ShowCommands(filters ...string) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	c.filters = removeFilters(c.filters, filters...)
+}
+
+// HideCommands is like Console.HideCommands, but the filters are only active in
+// this menu, in addition to the console ones. This can be used, for instance, to
+// hide the commands of a session menu that don't apply to the current target OS,
+// without affecting the other menus.
+func (m *Menu) HideCommands(filters ...string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.filters = addFilters(m.filters, filters...)
+}
+
+// ShowCommands deactivates filters previously activated with Menu.HideCommands.
+// Filters activated with Console.HideCommands are not affected, and keep hiding
+// their commands in this menu.
+func (m *Menu) ShowCommands(filters ...string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.filters = removeFilters(m.filters, filters...)
+}
+
+// activeFilters returns the filters active in the menu: the console ones and its own.
+func (m *Menu) activeFilters() []string {
+	return addFilters(m.console.activeFilters(), m.ownFilters()...)
+}
+
+// ownFilters returns a snapshot of the filters activated with Menu.HideCommands.
+func (m *Menu) ownFilters() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return append([]string(nil), m.filters...)
+}
+
+// activeFilters returns a snapshot of the console filters, taken under a
+// read lock, so that command trees can then be walked lock-free. (Holding a
+// write lock while walking them would both serialize every completion/highlight
+// render and risk a self-deadlock on the non-reentrant mutex.)
+func (c *Console) activeFilters() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return append([]string(nil), c.filters...)
+}
+
+// addFilters returns the active filters with the new ones
+// appended to them, ignoring empty and already active filters.
+func addFilters(active []string, filters ...string) []string {
+next:
+	for _, filt := range filters {
+		for _, filter := range active {
+			if filt == filter {
+				continue next
+			}
+		}
+		if filt != "" {
+			active = append(active, filt)
+		}
+	}
+
+	return active
+}
+
+// removeFilters returns the active filters without the given
+// ones, or an empty list if no filters are given.
+func removeFilters(active []string, filters ...string) []string {
 	updated := make([]string, 0)
 
 	if len(filters) == 0 {
-		c.filters = updated
-
-		return
+		return updated
 	}
 
 next:
-	for _, filt := range c.filters {
+	for _, filt := range active {
 		for _, filter := range filters {
 			if filt == filter {
 				continue next
@@ -76,5 +156,5 @@ next:
 		updated = append(updated, filt)
 	}
 
-	c.filters = updated
+	return updated
 }
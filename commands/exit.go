@@ -1,40 +1,26 @@
 package commands
 
 import (
-	"bufio"
-	"fmt"
 	"os"
-	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
 )
 
 // Exit returns a command to exit the console application.
-// The command will prompt the user to confirm quitting.
+// The command will prompt the user to confirm quitting,
+// unless it is given the --yes flag.
 func Exit() *cobra.Command {
 	exitCmd := &cobra.Command{
-		Use:     "exit",
-		Short:   "Exit the console application",
-		GroupID: "core",
+		Use:         "exit",
+		Short:       "Exit the console application",
+		GroupID:     "core",
+		Annotations: map[string]string{console.CommandConfirmKey: "Confirm exit?"},
 		Run: func(_ *cobra.Command, _ []string) {
-			exitCtrlD()
+			os.Exit(0)
 		},
 	}
 
 	return exitCmd
 }
-
-// exitCtrlD is a custom interrupt handler to use when the shell
-// readline receives an io.EOF error, which is returned with CtrlD.
-func exitCtrlD() {
-	reader := bufio.NewReader(os.Stdin)
-
-	fmt.Print("Confirm exit (Y/y): ")
-
-	text, _ := reader.ReadString('\n')
-	answer := strings.TrimSpace(text)
-
-	if strings.EqualFold(answer, "y") {
-		os.Exit(0)
-	}
-}
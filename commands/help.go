@@ -0,0 +1,247 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/reeflective/console"
+)
+
+// Help returns a `help [command | query]` command, meant to replace the cobra
+// default help command of menus (with root.SetHelpCommand). Without arguments,
+// it prints the help of the active menu. If the arguments are a command of the
+// active menu, it prints the help of this command, and otherwise it searches
+// all menus for commands matching the arguments, like the Apropos command.
+func Help(app *console.Console) *cobra.Command {
+	helpCmd := &cobra.Command{
+		Use:     "help [command | query]",
+		Short:   "Help about any command, or search for commands in all menus",
+		GroupID: "core",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+
+			if len(args) == 0 {
+				return root.Help()
+			}
+
+			if target, _, err := root.Find(args); err == nil && target != root && target != cmd {
+				return target.Help()
+			}
+
+			switchMenu, _ := cmd.Flags().GetBool("switch")
+
+			return apropos(app, cmd, strings.Join(args, " "), switchMenu)
+		},
+	}
+
+	helpCmd.Flags().BoolP("switch", "s", false, "Switch to the menu of the best match, when searching")
+
+	return helpCmd
+}
+
+// Apropos returns an `apropos <query>` command, which searches for commands in all
+// the console menus, matching the query (fuzzily) against their names and aliases,
+// their descriptions and their flags. For each match, the command prints its menu,
+// and whether the command is currently hidden by console filters. With --switch,
+// the console switches to the menu of the best match.
+func Apropos(app *console.Console) *cobra.Command {
+	aproposCmd := &cobra.Command{
+		Use:     "apropos <query>",
+		Short:   "Search for commands in all menus",
+		GroupID: "core",
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switchMenu, _ := cmd.Flags().GetBool("switch")
+
+			return apropos(app, cmd, strings.Join(args, " "), switchMenu)
+		},
+	}
+
+	aproposCmd.Flags().BoolP("switch", "s", false, "Switch to the menu of the best match")
+
+	return aproposCmd
+}
+
+// Match is a command matching an apropos query.
+type Match struct {
+	Menu    *console.Menu  // Menu in which the command lives.
+	Command *cobra.Command // The matching command.
+	Path    string         // Command path, without the menu root.
+	Filters []string       // Console filters currently hiding the command, if any.
+	Score   int            // Relevance of the match, the higher the better.
+}
+
+// Search returns all commands of all console menus matching the query, best
+// matches first. Each word of the query must match the command name or one of its
+// aliases (fuzzily), or appear in its descriptions or flags. Hidden commands are
+// ignored, but commands hidden by console filters are included: menus are searched
+// on their unfiltered command trees (see Menu.CommandTree), and the filters hiding
+// each match are those currently active in its menu.
+func Search(app *console.Console, query string) []Match {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil
+	}
+
+	var matches []Match
+
+	for _, menu := range app.Menus() {
+		var walk func(cmd *cobra.Command)
+		walk = func(cmd *cobra.Command) {
+			for _, sub := range cmd.Commands() {
+				if sub.Hidden || sub.Name() == "help" {
+					continue
+				}
+
+				if score := scoreCommand(sub, words); score > 0 {
+					matches = append(matches, Match{
+						Menu:    menu,
+						Command: sub,
+						Path:    strings.TrimSpace(strings.TrimPrefix(sub.CommandPath(), sub.Root().Name())),
+						Filters: menu.ActiveFiltersFor(sub),
+						Score:   score,
+					})
+				}
+
+				walk(sub)
+			}
+		}
+
+		walk(menu.CommandTree())
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	return matches
+}
+
+func apropos(app *console.Console, cmd *cobra.Command, query string, switchMenu bool) error {
+	matches := Search(app, query)
+	if len(matches) == 0 {
+		return fmt.Errorf("no command matches %q", query)
+	}
+
+	active := app.ActiveMenu()
+	table := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(table, "COMMAND\tMENU\tSTATUS\tDESCRIPTION")
+
+	for _, match := range matches {
+		menu := match.Menu.Name()
+		if menu == "" {
+			menu = "(default)"
+		}
+
+		if match.Menu == active {
+			menu += " *"
+		}
+
+		status := "available"
+		if len(match.Filters) > 0 {
+			status = "hidden (" + strings.Join(match.Filters, ", ") + ")"
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", match.Path, menu, status, match.Command.Short)
+	}
+
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	if switchMenu && matches[0].Menu != active {
+		app.SwitchMenu(matches[0].Menu.Name())
+		fmt.Fprintf(cmd.OutOrStdout(), "\nSwitched to menu %q\n", matches[0].Menu.Name())
+	}
+
+	return nil
+}
+
+// scoreCommand returns the relevance of cmd for the query words,
+// or 0 if any of the words does not match the command.
+func scoreCommand(cmd *cobra.Command, words []string) int {
+	total := 0
+
+	for _, word := range words {
+		best := fuzzyScore(word, cmd.Name()) * 3
+
+		for _, alias := range cmd.Aliases {
+			best = max(best, fuzzyScore(word, alias)*2)
+		}
+
+		if strings.Contains(strings.ToLower(cmd.Short), word) {
+			best = max(best, 2*len(word))
+		}
+
+		if strings.Contains(strings.ToLower(cmd.Long), word) {
+			best = max(best, len(word))
+		}
+
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			if flag.Hidden {
+				return
+			}
+
+			if strings.Contains(flag.Name, word) || strings.Contains(strings.ToLower(flag.Usage), word) {
+				best = max(best, len(word))
+			}
+		})
+
+		if best == 0 {
+			return 0
+		}
+
+		total += best
+	}
+
+	return total
+}
+
+// fuzzyScore returns how well pattern matches text, as a subsequence
+// of its characters (case-insensitive), or 0 if it does not match. Exact
+// matches, prefixes, consecutive characters and word starts rank higher.
+func fuzzyScore(pattern, text string) int {
+	text = strings.ToLower(text)
+
+	switch {
+	case text == pattern:
+		return 4 * len(pattern)
+	case strings.HasPrefix(text, pattern):
+		return 3 * len(pattern)
+	}
+
+	runes := []rune(text)
+	score, pos, prev := 0, 0, -2
+
+	for _, char := range pattern {
+		for pos < len(runes) && runes[pos] != char {
+			pos++
+		}
+
+		if pos == len(runes) {
+			return 0
+		}
+
+		score++
+
+		if pos == prev+1 {
+			score++
+		}
+
+		if pos == 0 || !unicode.IsLetter(runes[pos-1]) {
+			score++
+		}
+
+		prev = pos
+		pos++
+	}
+
+	return score
+}
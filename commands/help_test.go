@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+func newHelpConsole() *console.Console {
+	app := console.New("test")
+	run := func(*cobra.Command, []string) {}
+
+	app.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddGroup(&cobra.Group{ID: "core", Title: "core"})
+		root.SetHelpCommand(Help(app))
+		root.AddCommand(Apropos(app))
+
+		upload := &cobra.Command{Use: "upload", Short: "Upload a file to the server", Run: run}
+		upload.Flags().Bool("compress", false, "compress the file before sending it")
+		root.AddCommand(upload, &cobra.Command{Use: "secret", Short: "Upload secrets", Hidden: true, Run: run})
+
+		return root
+	})
+
+	app.NewMenu("client").SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddCommand(
+			&cobra.Command{Use: "download", Aliases: []string{"dl"}, Short: "Download a file", Run: run},
+			&cobra.Command{
+				Use:         "screenshot",
+				Short:       "Take a screenshot",
+				Annotations: map[string]string{console.CommandFilterKey: "headless"},
+				Run:         run,
+			},
+			&cobra.Command{
+				Use:         "record",
+				Short:       "Record the terminal",
+				Annotations: map[string]string{console.CommandFilterKey: "!headless"},
+				Run:         run,
+			},
+		)
+
+		return root
+	})
+
+	return app
+}
+
+func TestSearch(t *testing.T) {
+	app := newHelpConsole()
+
+	// Commands hidden when a filter is inactive are found too.
+	if matches := Search(app, "record"); len(matches) != 1 {
+		t.Fatalf("Search(record) = %+v, want the command hidden without the headless filter", matches)
+	}
+
+	app.HideCommands("headless")
+
+	paths := func(matches []Match) []string {
+		var paths []string
+		for _, match := range matches {
+			paths = append(paths, match.Menu.Name()+":"+match.Path)
+		}
+
+		return paths
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"download", []string{"client:download"}},
+		{"dl", []string{"client:download"}},
+		{"file", []string{":upload", "client:download"}},
+		{"compress", []string{":upload"}},
+		{"upload file", []string{":upload"}},
+		{"scrsht", []string{"client:screenshot"}},
+		{"nothing", nil},
+	}
+
+	for _, tc := range tests {
+		if got := paths(Search(app, tc.query)); strings.Join(got, " ") != strings.Join(tc.want, " ") {
+			t.Errorf("Search(%q) = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+
+	if matches := Search(app, "screenshot"); len(matches) != 1 || strings.Join(matches[0].Filters, ",") != "headless" {
+		t.Fatalf("Search(screenshot) = %+v, want the command hidden by the headless filter", matches)
+	}
+}
+
+func TestApropos(t *testing.T) {
+	app := newHelpConsole()
+	menu := app.ActiveMenu()
+
+	res, err := menu.Exec(context.Background(), "apropos screen")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out := string(res.Stdout); !strings.Contains(out, "screenshot") || !strings.Contains(out, "client") {
+		t.Fatalf("apropos output = %q, want the screenshot command of the client menu", out)
+	}
+
+	// Help on a command of the active menu prints its help.
+	res, _ = menu.Exec(context.Background(), "help upload")
+	if out := string(res.Stdout); !strings.Contains(out, "--compress") {
+		t.Fatalf("help upload output = %q, want the command help", out)
+	}
+
+	// Help on anything else searches all menus, and may switch to the best one.
+	if _, err := menu.Exec(context.Background(), "help --switch download"); err != nil {
+		t.Fatal(err)
+	}
+
+	if name := app.ActiveMenu().Name(); name != "client" {
+		t.Fatalf("active menu = %q, want client", name)
+	}
+}
+
+func TestSearchWithoutGenerator(t *testing.T) {
+	app := console.New("test")
+	menu := app.ActiveMenu()
+
+	root := &cobra.Command{}
+	root.AddCommand(
+		&cobra.Command{Use: "noop", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{
+			Use:         "screenshot",
+			Annotations: map[string]string{console.CommandFilterKey: "headless"},
+			Run:         func(*cobra.Command, []string) {},
+		},
+	)
+	menu.Command = root
+
+	// Executions hide the filtered commands in the menu tree,
+	// which must not change the results of later searches.
+	app.HideCommands("headless")
+
+	if err := menu.RunCommandArgs(context.Background(), []string{"noop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if matches := Search(app, "screenshot"); len(matches) != 1 || strings.Join(matches[0].Filters, ",") != "headless" {
+		t.Fatalf("Search(screenshot) = %+v, want the command hidden by the headless filter", matches)
+	}
+
+	app.ShowCommands("headless")
+
+	if matches := Search(app, "screenshot"); len(matches) != 1 || len(matches[0].Filters) != 0 {
+		t.Fatalf("Search(screenshot) = %+v, want the command available", matches)
+	}
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+// Messages returns a `messages [menu...]` command, printing the messages queued
+// by menus while they were not active (see console.Menu.Messages), oldest first,
+// and marking them read. Without arguments, the messages of all menus are shown.
+// With --clear, the messages are removed instead of being printed.
+func Messages(app *console.Console) *cobra.Command {
+	messagesCmd := &cobra.Command{
+		Use:     "messages [menu...]",
+		Short:   "Show or clear the messages printed to inactive menus",
+		GroupID: "core",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			menus := app.Menus()
+
+			if len(args) > 0 {
+				menus = menus[:0]
+
+				for _, name := range args {
+					menu := app.Menu(name)
+					if menu == nil {
+						return fmt.Errorf("no menu named %q", name)
+					}
+
+					menus = append(menus, menu)
+				}
+			}
+
+			if clear, _ := cmd.Flags().GetBool("clear"); clear {
+				for _, menu := range menus {
+					menu.ClearMessages()
+				}
+
+				return nil
+			}
+
+			printMessages(cmd, menus)
+
+			return nil
+		},
+		ValidArgsFunction: func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+			var names []string
+
+			for _, menu := range app.Menus() {
+				if menu.Name() != "" {
+					names = append(names, menu.Name())
+				}
+			}
+
+			return names, cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+
+	messagesCmd.Flags().BoolP("clear", "c", false, "Remove the messages instead of printing them")
+
+	return messagesCmd
+}
+
+// printMessages prints the messages of the menus as a table.
+func printMessages(cmd *cobra.Command, menus []*console.Menu) {
+	table := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	count := 0
+
+	fmt.Fprintln(table, "TIME\tMENU\tLEVEL\tMESSAGE")
+
+	for _, menu := range menus {
+		name := menu.Name()
+		if name == "" {
+			name = "(default)"
+		}
+
+		for _, msg := range menu.ReadMessages() {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", msg.Time.Format(time.TimeOnly), name, msg.Level, msg.Text)
+			count++
+		}
+	}
+
+	if count == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No messages.")
+		return
+	}
+
+	table.Flush()
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestMessages(t *testing.T) {
+	app := newHelpConsole()
+	app.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddGroup(&cobra.Group{ID: "core", Title: "core"})
+		root.AddCommand(Messages(app))
+
+		return root
+	})
+
+	client := app.Menu("client")
+	client.Printf("session opened\n")
+
+	res, err := app.ActiveMenu().Exec(context.Background(), "messages client")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out := string(res.Stdout); !strings.Contains(out, "client") || !strings.Contains(out, "INFO") ||
+		!strings.Contains(out, "session opened") {
+		t.Fatalf("messages printed:\n%s", out)
+	}
+
+	if client.UnreadMessages() != 0 {
+		t.Fatal("listed messages should be marked read")
+	}
+
+	if _, err := app.ActiveMenu().Exec(context.Background(), "messages --clear"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, _ = app.ActiveMenu().Exec(context.Background(), "messages")
+	if out := string(res.Stdout); out != "No messages.\n" {
+		t.Fatalf("messages after --clear printed %q", out)
+	}
+
+	if _, err := app.ActiveMenu().Exec(context.Background(), "messages nope"); err == nil {
+		t.Fatal("messages with an unknown menu should fail")
+	}
+}
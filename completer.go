@@ -1,48 +1,183 @@
 package console
 
 import (
+	"encoding/json"
+	"runtime/debug"
 	"strings"
+	"sync"
 
-	"github.com/carapace-sh/carapace"
+	// The carapace package initializes the completer.Complete function.
+	_ "github.com/carapace-sh/carapace"
 	"github.com/carapace-sh/carapace/pkg/style"
 	completer "github.com/carapace-sh/carapace/pkg/x"
 	"github.com/reeflective/readline"
+	"github.com/spf13/cobra"
 
 	"github.com/reeflective/console/internal/command"
 	"github.com/reeflective/console/internal/completion"
 	"github.com/reeflective/console/internal/line"
 )
 
+// Completion is a completion candidate.
+type Completion = readline.Completion
+
+// Completions holds the completions produced for an input line,
+// along with the data the shell uses to display and insert them.
+type Completions struct {
+	// Values are the candidates, as inserted in the line: they already
+	// include Prefix, and a trailing space unless NoSpace matches them.
+	Values []Completion
+
+	// Usage is the usage string of the command, flag or argument completed.
+	Usage string
+
+	// Messages are the completion errors and status messages.
+	Messages []string
+
+	// Prefix is the quote/escape sequence added to all values, when the
+	// word being completed started with one.
+	Prefix string
+
+	// LinePrefix is, when the word being completed is quoted, the part
+	// of this word already in the line (opening quote included), which
+	// is replaced by the inserted candidate.
+	LinePrefix string
+
+	// NoSpace holds the suffix characters after which the shell does not
+	// insert a space when accepting a candidate ('*' meaning all of them).
+	NoSpace string
+}
+
+// Complete returns the completions for an input line, with the cursor at the
+// given position (in runes: a negative or out of range cursor means the end of
+// line). The line is completed with a new command tree, hiding the commands
+// filtered in the menu, so that the menu does not have to be the active one,
+// and this can be called while the console executes or completes commands.
+// Completions are produced exactly like those used by the shell, which makes
+// this function suitable for testing them.
+func (m *Menu) Complete(input string, cursor int) Completions {
+	line := []rune(input)
+	if cursor < 0 || cursor > len(line) {
+		cursor = len(line)
+	}
+
+	return m.completeTree(m.newCommands(m.activeFilters()), line, cursor)
+}
+
 func (c *Console) complete(input []rune, pos int) readline.Completions {
-	menu := c.activeMenu()
+	return c.shellCompletions(c.activeMenu().complete(input, pos))
+}
+
+// shellCompletions converts completions to those used by the shell.
+func (c *Console) shellCompletions(completions Completions) readline.Completions {
+	// Assign both completions and command/flags/args usage strings.
+	comps := readline.CompleteRaw(completions.Values)
+	comps = comps.Usage("%s", completions.Usage)
+	comps = c.justifyCommandComps(comps)
 
-	// Ensure the carapace library is called so that the function
-	// completer.Complete() variable is correctly initialized before use.
-	carapace.Gen(menu.Command)
-	command.HideCarapace(menu.Command)
+	// Completion status/errors
+	for _, msg := range completions.Messages {
+		comps = comps.Merge(readline.CompleteMessage(msg))
+	}
+
+	// Suffix matchers for the completions if any.
+	if len(completions.NoSpace) > 0 {
+		comps = comps.NoSpace([]rune(completions.NoSpace)...)
+	}
+
+	comps.PREFIX = completions.LinePrefix
+
+	return comps
+}
+
+// complete computes the completions for an input line in this menu.
+func (m *Menu) complete(input []rune, pos int) Completions {
+	comps := m.completeTree(m.Command, input, pos)
+
+	// Finally, reset our command tree for the next call. Only the commands need
+	// regenerating here: the prompt is already bound and no command output was
+	// produced, so the full resetPreRun would just be wasted work per keystroke.
+	// (resetCommands already re-hides filtered commands.)
+	m.resetCommands()
+
+	return comps
+}
+
+// carapaceMutex serializes completions, since carapace keeps the completion
+// actions of all commands in a global storage, which they read and modify.
+// Commands being executed hold it for reading until cobra has initialized
+// them (see waitInitializers).
+var carapaceMutex sync.RWMutex
+
+// waitInitializers makes completions wait until cobra has run its initializers
+// for the execution of the target command, since carapace registers some which
+// use its storage (see carapace.Gen). They run before the command arguments are
+// validated, which releases the completions. The returned function must be called
+// once the command returns: it releases them too, and restores the validation.
+func waitInitializers(target *cobra.Command) (release func()) {
+	carapaceMutex.RLock()
+	initialized := sync.OnceFunc(carapaceMutex.RUnlock)
+
+	validate := target.Args
+	target.Args = func(cmd *cobra.Command, args []string) error {
+		initialized()
+
+		if validate == nil {
+			return cobra.ArbitraryArgs(cmd, args)
+		}
+
+		return validate(cmd, args)
+	}
+
+	return func() {
+		initialized()
+		target.Args = validate
+	}
+}
+
+// completeTree computes the completions for an input line with a command tree
+// of this menu, whose flags state is modified by the completion engine.
+func (m *Menu) completeTree(root *cobra.Command, input []rune, pos int) (comps Completions) {
+	carapaceMutex.Lock()
+	defer carapaceMutex.Unlock()
+
+	// A panicking completion must not take the console down.
+	defer m.recoverCompletion(&comps)
+
+	panicked, restore := recoverCompletionFuncs(root)
+	defer restore()
+
+	// The tree is not given to carapace.Gen, which would register a global cobra
+	// initializer for each completion, run by every command execution meanwhile.
+	command.HideCarapace(root)
 
 	// Split the line as shell words, only using
 	// what the right buffer (up to the cursor)
-	args, prefixComp, prefixLine := completion.SplitArgs(input, pos, c.getEscapeMode())
-	command.ResetCompletionFlagState(menu.Command, args)
+	args, prefixComp, prefixLine := completion.SplitArgs(input, pos, m.console.getEscapeMode())
+	command.ResetCompletionFlagState(root, args)
 
 	// Prepare arguments for the carapace completer
 	// (we currently need those two dummies for avoiding a panic).
-	args = append([]string{c.name, "_carapace"}, args...)
+	args = append([]string{m.console.name, "_carapace"}, args...)
 
 	// Call the completer with our current command context.
-	completions, err := completer.Complete(menu.Command, args...)
+	completions, err := completer.Complete(root, args...)
 
 	// The completions are never nil: fill out our own object
 	// with everything it contains, regardless of errors.
-	raw := make([]readline.Completion, 0, len(completions.Values))
+	comps = Completions{
+		Values:     make([]Completion, 0, len(completions.Values)),
+		Usage:      completions.Usage,
+		Prefix:     prefixComp,
+		LinePrefix: prefixLine,
+	}
 
 	for _, val := range completions.Values {
 		if strings.TrimSpace(val.Value) == "_carapace" {
 			continue
 		}
 
-		comp := readline.Completion{
+		comp := Completion{
 			Value:       line.UnescapeValue(prefixComp, prefixLine, val.Value),
 			Display:     val.Display,
 			Description: val.Description,
@@ -61,45 +196,119 @@ func (c *Console) complete(input []rune, pos int) readline.Completions {
 			comp.Tag = "flags"
 		}
 
-		raw = append(raw, comp)
+		// If we have a quote/escape sequence unaccounted
+		// for in our completions, add it to all of them.
+		comp.Value = prefixComp + comp.Value
+
+		comps.Values = append(comps.Values, comp)
 	}
 
-	// Assign both completions and command/flags/args usage strings.
-	comps := readline.CompleteRaw(raw)
-	comps = comps.Usage("%s", completions.Usage)
-	comps = c.justifyCommandComps(comps)
+	// If a completion function panicked, only report it.
+	if panicErr := panicked(); panicErr != nil {
+		return m.completionPanic(*panicErr)
+	}
 
 	// If any errors arose from the completion call itself.
 	if err != nil {
-		comps = readline.CompleteMessage("failed to load config: " + err.Error())
+		comps.Values = nil
+		comps.Usage = ""
+		comps.Messages = append(comps.Messages, "failed to load config: "+err.Error())
 	}
 
 	// Completion status/errors
-	for _, msg := range completions.Messages.Get() {
-		comps = comps.Merge(readline.CompleteMessage(msg))
-	}
+	comps.Messages = append(comps.Messages, completions.Messages.Get()...)
 
 	// Suffix matchers for the completions if any.
-	suffixes, err := completions.Nospace.MarshalJSON()
-	if len(suffixes) > 0 && err == nil {
-		comps = comps.NoSpace([]rune(string(suffixes))...)
+	if suffixes, err := completions.Nospace.MarshalJSON(); err == nil {
+		_ = json.Unmarshal(suffixes, &comps.NoSpace)
 	}
 
-	// If we have a quote/escape sequence unaccounted
-	// for in our completions, add it to all of them.
-	comps = comps.Prefix(prefixComp)
-	comps.PREFIX = prefixLine
-
-	// Finally, reset our command tree for the next call. Only the commands need
-	// regenerating here: the prompt is already bound and no command output was
-	// produced, so the full resetPreRun would just be wasted work per keystroke.
-	// (resetCommands already re-hides filtered commands.)
 	completer.ClearStorage()
-	menu.resetCommands()
 
 	return comps
 }
 
+// recoverCompletion recovers from a panic of the completion engine,
+// replacing the completions with those of completionPanic.
+// It must be deferred.
+func (m *Menu) recoverCompletion(comps *Completions) {
+	if value := recover(); value != nil {
+		*comps = m.completionPanic(PanicError{
+			Source: "completer",
+			Value:  value,
+			Stack:  debug.Stack(),
+		})
+	}
+}
+
+// completionPanic gives a completion PanicError to the menu error
+// handler, and returns completions only made of the error message.
+func (m *Menu) completionPanic(err PanicError) Completions {
+	m.ErrorHandler(err)
+	completer.ClearStorage()
+
+	return Completions{Messages: []string{err.Error()}}
+}
+
+// recoverCompletionFuncs wraps the positional completion functions of all
+// commands, so that their panics are recovered: carapace invokes positional
+// completions in a goroutine of its own (see carapace.Batch), where the panics
+// would crash the console, since recoverCompletion only recovers those of the
+// calling goroutine. The carapace actions of commands are invoked there too, but
+// can't be wrapped without carapace.Gen, so that their panics are not recovered.
+// The returned function returns the first recovered panic, if any, and the
+// restore one unwraps the functions, so that trees completed several times
+// (those of menus without a generator) don't stack wrappers.
+func recoverCompletionFuncs(root *cobra.Command) (panicked func() *PanicError, restore func()) {
+	var (
+		mutex    sync.Mutex
+		panicErr *PanicError
+		wrapped  = make(map[*cobra.Command]cobra.CompletionFunc)
+	)
+
+	var wrap func(cmd *cobra.Command)
+
+	wrap = func(cmd *cobra.Command) {
+		if complete := cmd.ValidArgsFunction; complete != nil {
+			wrapped[cmd] = complete
+			cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, prefix string) (_ []string, _ cobra.ShellCompDirective) {
+				defer func() {
+					if value := recover(); value != nil {
+						mutex.Lock()
+						if panicErr == nil {
+							panicErr = &PanicError{Source: "completer", Value: value, Stack: debug.Stack()}
+						}
+						mutex.Unlock()
+					}
+				}()
+
+				return complete(cmd, args, prefix)
+			}
+		}
+
+		for _, child := range cmd.Commands() {
+			wrap(child)
+		}
+	}
+
+	wrap(root)
+
+	panicked = func() *PanicError {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return panicErr
+	}
+
+	restore = func() {
+		for cmd, complete := range wrapped {
+			cmd.ValidArgsFunction = complete
+		}
+	}
+
+	return panicked, restore
+}
+
 // justifyCommandComps justifies the descriptions for all commands in all groups
 // to the same level, for prettiness. Also, removes any coloring from them, as currently,
 // the carapace engine does add coloring to each group, and we don't want this.
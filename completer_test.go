@@ -1,9 +1,12 @@
 package console
 
 import (
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/carapace-sh/carapace"
 	"github.com/reeflective/readline"
 	"github.com/spf13/cobra"
 )
@@ -76,6 +79,82 @@ func TestCompleteResetsArgsLenAtDash(t *testing.T) {
 	}
 }
 
+func TestMenuComplete(t *testing.T) {
+	c := New("test")
+	menu := c.NewMenu("other")
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		run := func(*cobra.Command, []string) {}
+		cmd := &cobra.Command{Use: "connect", Short: "Connect to a host", Run: run}
+		cmd.Flags().String("proto", "", "protocol to use")
+		root.AddCommand(cmd, &cobra.Command{Use: "config", Short: "Show configuration", Run: run})
+
+		carapace.Gen(cmd).FlagCompletion(carapace.ActionMap{
+			"proto": carapace.ActionValues("tcp", "udp").Usage("protocol"),
+		})
+		carapace.Gen(cmd).PositionalCompletion(
+			carapace.ActionValues("host/a", "host/b").NoSpace('/'),
+		)
+
+		return root
+	})
+
+	// The menu is not active, and completes its own commands.
+	comps := menu.Complete("con", -1)
+	if got := completionValues(readline.CompleteRaw(comps.Values)); !reflect.DeepEqual(got, []string{"config ", "connect "}) {
+		t.Fatalf("command completions = %q, want [config connect]", got)
+	}
+
+	for _, comp := range comps.Values {
+		if comp.Description == "" || comp.Tag != "commands" {
+			t.Fatalf("command completion %+v lacks description or commands tag", comp)
+		}
+	}
+
+	// Flag arguments, with their usage.
+	comps = menu.Complete("connect --proto ", -1)
+	if got := completionValues(readline.CompleteRaw(comps.Values)); !reflect.DeepEqual(got, []string{"tcp ", "udp "}) {
+		t.Fatalf("flag completions = %q, want [tcp udp]", got)
+	}
+	if comps.Usage != "protocol" {
+		t.Fatalf("flag usage = %q, want %q", comps.Usage, "protocol")
+	}
+
+	// The cursor restricts the completed word, and no-space suffixes are exposed.
+	comps = menu.Complete("connect ho --proto tcp", len("connect ho"))
+	if got := completionValues(readline.CompleteRaw(comps.Values)); !reflect.DeepEqual(got, []string{"host/a ", "host/b "}) {
+		t.Fatalf("positional completions = %q, want [host/a host/b]", got)
+	}
+	if comps.NoSpace != "/" {
+		t.Fatalf("no-space suffixes = %q, want %q", comps.NoSpace, "/")
+	}
+}
+
+func TestCompleteWithoutGenerator(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	var depths []int
+
+	menu.AddCommand(&cobra.Command{
+		Use: "target",
+		Run: func(*cobra.Command, []string) {},
+		ValidArgsFunction: func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+			depths = append(depths, runtime.Callers(0, make([]uintptr, 256)))
+			return []string{"host"}, cobra.ShellCompDirectiveNoFileComp
+		},
+	})
+
+	for range 20 {
+		menu.Complete("target ", -1)
+	}
+
+	// Completion functions are wrapped for each completion, not once more each time.
+	if len(depths) != 20 || depths[0] != depths[len(depths)-1] {
+		t.Fatalf("stack depths of the completion function = %v, want them constant", depths)
+	}
+}
+
 func completionValues(comps readline.Completions) []string {
 	var values []string
 
@@ -1,7 +1,9 @@
 package console
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 
@@ -66,3 +68,36 @@ func TestConcurrentStateAccess(t *testing.T) {
 
 	wg.Wait()
 }
+
+// TestCompleteWhileRunning completes lines in the active menu while the
+// console runs a script in it, like a control client would (run with -race).
+func TestCompleteWhileRunning(t *testing.T) {
+	c := New("test")
+
+	menu := c.ActiveMenu()
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{Use: "noop", Run: func(*cobra.Command, []string) {}})
+
+		return root
+	})
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for range 20 {
+			if comps := menu.Complete("no", -1); len(comps.Values) != 1 {
+				t.Errorf("completions = %+v, want noop", comps.Values)
+				return
+			}
+		}
+	}()
+
+	if err := c.RunScript(context.Background(), strings.NewReader(strings.Repeat("noop\n", 20))); err != nil {
+		t.Fatal(err)
+	}
+
+	<-done
+}
@@ -0,0 +1,64 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console/internal/command"
+)
+
+// CommandConfirmKey should be used as a key in a cobra.Annotation map. The
+// value is a question asked to the user before running the command, which
+// only runs if the user answers yes:
+//
+//	cmd.Annotations = map[string]string{console.CommandConfirmKey: "Delete all loot?"}
+//
+// Such commands are given a --yes flag (unless they already have one) with
+// which the confirmation is given in advance. When the command is not run
+// from the console prompt (in scripts, sessions, with Menu.RunCommandArgs
+// or Menu.Exec), nobody can be asked: the Console.ScriptedConfirm policy applies.
+const CommandConfirmKey = command.ConfirmKey
+
+// ConfirmPolicy is how commands requiring a confirmation (see CommandConfirmKey)
+// are handled when they are not run from the console prompt.
+type ConfirmPolicy int
+
+const (
+	// ConfirmRefuse refuses to run commands that are not given
+	// the --yes flag, with an ErrNotConfirmed error. This is the default.
+	ConfirmRefuse ConfirmPolicy = iota
+
+	// ConfirmAssume runs commands as if the user had answered yes.
+	ConfirmAssume
+)
+
+// ErrNotConfirmed is returned (wrapped) when a command requiring a
+// confirmation is not confirmed: it is never run, nor are the pre-run hooks.
+var ErrNotConfirmed = errors.New("command not confirmed")
+
+// confirmRun returns an error if cmd requires a confirmation that is not given,
+// either in advance with args, by the user, or by the console scripted policy.
+func (c *Console) confirmRun(ctx context.Context, cmd *cobra.Command, args []string) error {
+	question := command.Confirmation(cmd)
+	if question == "" || command.Confirmed(cmd, args) {
+		return nil
+	}
+
+	if !interactive(ctx) {
+		if c.ScriptedConfirm == ConfirmAssume {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %s requires a confirmation, use --%s to run it",
+			ErrNotConfirmed, commandPath(cmd), command.YesFlag)
+	}
+
+	if yes, err := c.Confirm(ctx, question); err != nil || !yes {
+		return fmt.Errorf("%w: %s", ErrNotConfirmed, commandPath(cmd))
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func newConfirmConsole(ran *int) *Console {
+	c := New("test")
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{
+			Use:         "wipe",
+			Annotations: map[string]string{CommandConfirmKey: "Delete all loot?"},
+			Run:         func(*cobra.Command, []string) { *ran++ },
+		})
+
+		return root
+	})
+
+	return c
+}
+
+func TestConfirmScripted(t *testing.T) {
+	var ran int
+
+	c := newConfirmConsole(&ran)
+	menu := c.ActiveMenu()
+
+	if _, err := menu.Exec(context.Background(), "wipe"); !errors.Is(err, ErrNotConfirmed) || ran != 0 {
+		t.Fatalf("wipe = %v (ran %d times), want ErrNotConfirmed", err, ran)
+	}
+
+	if _, err := menu.Exec(context.Background(), "wipe --yes"); err != nil || ran != 1 {
+		t.Fatalf("wipe --yes = %v (ran %d times), want it to run", err, ran)
+	}
+
+	c.ScriptedConfirm = ConfirmAssume
+
+	if _, err := menu.Exec(context.Background(), "wipe"); err != nil || ran != 2 {
+		t.Fatalf("wipe with ConfirmAssume = %v (ran %d times), want it to run", err, ran)
+	}
+}
+
+func TestConfirmRunCommandArgs(t *testing.T) {
+	var ran int
+
+	c := newConfirmConsole(&ran)
+
+	// Pending keys would answer the question if the command was interactive.
+	c.Shell().Keys.Feed(false, []rune("y\r")...)
+
+	if err := c.ActiveMenu().RunCommandArgs(context.Background(), []string{"wipe"}); !errors.Is(err, ErrNotConfirmed) || ran != 0 {
+		t.Fatalf("wipe = %v (ran %d times), want ErrNotConfirmed without prompting", err, ran)
+	}
+}
+
+func TestConfirmInteractive(t *testing.T) {
+	var ran int
+
+	c := newConfirmConsole(&ran)
+
+	for _, tc := range []struct {
+		keys    string
+		runs    int
+		refused bool
+	}{
+		{"wipe\rn\r", 0, true},
+		{"wipe\r\r", 0, true},
+		{"wipe\ry\r", 1, false},
+		{"wipe --yes\r", 2, false},
+	} {
+		c.Shell().Keys.Feed(false, []rune(tc.keys)...)
+
+		err := c.RunOnce(context.Background())
+		if ran != tc.runs || errors.Is(err, ErrNotConfirmed) != tc.refused {
+			t.Fatalf("%q: ran %d times with error %v, want %d runs (refused: %v)", tc.keys, ran, err, tc.runs, tc.refused)
+		}
+	}
+
+	// Answers are not written to the menu history.
+	hist := c.ActiveMenu().histories[c.ActiveMenu().historyNames[0]]
+	for i := range hist.Len() {
+		if line, _ := hist.GetLine(i); line == "y" || line == "n" {
+			t.Fatalf("history has the answer %q", line)
+		}
+	}
+}
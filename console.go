@@ -3,9 +3,11 @@ package console
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/reeflective/readline"
 
@@ -23,18 +25,24 @@ type highlightCache struct {
 // Console is an integrated console application instance.
 type Console struct {
 	// Application
-	name          string           // Used in the prompt, and for readline `.inputrc` application-specific settings.
-	shell         *readline.Shell  // Provides readline functionality (inputs, completions, hints, history)
-	printLogo     func(c *Console) // Simple logo printer.
-	cmdHighlight  string           // Ansi code for highlighting of command in default highlighter. Green by default.
-	flagHighlight string           // Ansi code for highlighting of flag in default highlighter. Grey by default.
-	menus         map[string]*Menu // Different command trees, prompt engines, etc.
-	current       *Menu            // Cached pointer to the active menu (guarded by mutex).
-	filters       []string         // Hide commands based on their attributes and current context.
-	escapeMode    line.EscapeMode  // How input lines are split into words (guarded by mutex).
-	isExecuting   atomic.Bool      // Used by log functions, which need to adapt behavior (print the prompt, etc.)
-	printed       bool             // Used to adjust asynchronous messages too.
-	mutex         *sync.RWMutex    // Concurrency management.
+	name          string            // Used in the prompt, and for readline `.inputrc` application-specific settings.
+	shell         *readline.Shell   // Provides readline functionality (inputs, completions, hints, history)
+	printLogo     func(c *Console)  // Simple logo printer.
+	cmdHighlight  string            // Ansi code for highlighting of command in default highlighter. Green by default.
+	flagHighlight string            // Ansi code for highlighting of flag in default highlighter. Grey by default.
+	menus         map[string]*Menu  // Different command trees, prompt engines, etc.
+	current       *Menu             // Cached pointer to the active menu (guarded by mutex).
+	filters       []string          // Hide commands based on their attributes and current context.
+	sessions      map[*Session]bool // Remote sessions currently running.
+	principal     *Principal        // Identity whose roles are checked against commands (guarded by mutex).
+	escapeMode    line.EscapeMode   // How input lines are split into words (guarded by mutex).
+	isExecuting   atomic.Bool       // Used by log functions, which need to adapt behavior (print the prompt, etc.)
+	status        atomic.Int32      // Exit status of the last executed command.
+	printed       bool              // Used to adjust asynchronous messages too.
+	mutex         *sync.RWMutex     // Concurrency management.
+	dialogs       sync.Mutex        // Serializes the dialogs read with the shell.
+	progress      progressDisplay   // Status of the progresses shown with Console.Progress.
+	running       commandRegistry   // Commands which have not returned yet.
 
 	// hlCache memoizes the last syntax-highlighting result. The highlighter is
 	// called on every render (even when only the cursor moved), so caching the
@@ -77,9 +85,16 @@ type Console struct {
 	// Signals is the set of OS signals the console traps while a command is
 	// running. When one is received, the running command's context is
 	// cancelled (see StartContext for the cancellation model). If empty, the
-	// console defaults to SIGINT, SIGTERM and SIGQUIT.
+	// console defaults to SIGINT, SIGTERM and SIGQUIT. Menus can trap other
+	// signals, and take other actions for them (see Menu.SetSignalAction).
 	Signals []os.Signal
 
+	// GracePeriod is how long the console waits for a command interrupted by
+	// one of the Signals to return, before abandoning it and reading input again
+	// (see AbandonedError). A second signal abandons the command immediately.
+	// It is 2 seconds by default: if zero, commands are abandoned right away.
+	GracePeriod time.Duration
+
 	// PreReadlineHooks - All the functions in this list will be executed,
 	// in their respective orders, before the console starts reading
 	// any user input (ie, before redrawing the prompt).
@@ -101,6 +116,22 @@ type Console struct {
 	// These hooks are distinct from the cobra.PreRun() or OnFinalize hooks,
 	// and might be used in combination with them.
 	PostCmdRunHooks []func() error
+
+	// ScriptedConfirm is how commands requiring a confirmation (see CommandConfirmKey)
+	// are handled when they are not run from the console prompt, that is, when they
+	// are run with RunScript, Menu.RunCommandArgs, Menu.Exec, or in sessions.
+	// Defaults to ConfirmRefuse.
+	ScriptedConfirm ConfirmPolicy
+
+	// PromptMissing, when true, makes the console ask the user for the required
+	// flags and positional arguments missing from command lines typed at the prompt,
+	// instead of failing them. Candidates for each of them are their completions.
+	PromptMissing bool
+
+	// PermissionAudit, if not nil, is called with every command execution
+	// refused because the principal does not hold the roles it requires
+	// (see CommandRolesKey), whether in the console or in a session.
+	PermissionAudit func(err PermissionError)
 }
 
 // New - Instantiates a new console application, with sane but powerful defaults.
@@ -109,10 +140,11 @@ type Console struct {
 // The app parameter is an optional name of the application using this console.
 func New(app string) *Console {
 	console := &Console{
-		name:  app,
-		shell: readline.NewShell(inputrc.WithApp(strings.ToLower(app))),
-		menus: make(map[string]*Menu),
-		mutex: &sync.RWMutex{},
+		name:     app,
+		shell:    readline.NewShell(inputrc.WithApp(strings.ToLower(app))),
+		menus:    make(map[string]*Menu),
+		sessions: make(map[*Session]bool),
+		mutex:    &sync.RWMutex{},
 	}
 
 	// Quality of life improvements.
@@ -144,10 +176,16 @@ func New(app string) *Console {
 	// Defaults
 	console.EmptyChars = []rune{' ', '\t'}
 	console.Signals = append([]os.Signal(nil), defaultTrapSignals...)
+	console.GracePeriod = defaultGracePeriod
 
 	return console
 }
 
+// Name returns the name of the application using this console.
+func (c *Console) Name() string {
+	return c.name
+}
+
 // Shell returns the console readline shell instance, so that the user can
 // further configure it or use some of its API for lower-level stuff.
 func (c *Console) Shell() *readline.Shell {
@@ -244,10 +282,31 @@ func (c *Console) Menu(name string) *Menu {
 	return c.menus[name]
 }
 
+// Menus returns all the console menus, sorted by name.
+func (c *Console) Menus() []*Menu {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	menus := make([]*Menu, 0, len(c.menus))
+	for _, menu := range c.menus {
+		menus = append(menus, menu)
+	}
+
+	sort.Slice(menus, func(i, j int) bool {
+		return menus[i].name < menus[j].name
+	})
+
+	return menus
+}
+
 // SwitchMenu - Given a name, the console switches its command menu:
 // The next time the console rebinds all of its commands, it will only bind those
 // that belong to this new menu. If the menu is invalid, i.e that no commands
 // are bound to this menu name, the current menu is kept.
+//
+// This always switches the menu of the console itself: commands run in a
+// session (see Session) which must switch the menu of the session instead
+// should use SessionFromContext(cmd.Context()).SwitchMenu when it is not nil.
 func (c *Console) SwitchMenu(menu string) {
 	c.mutex.Lock()
 
@@ -283,6 +342,9 @@ func (c *Console) SwitchMenu(menu string) {
 
 	// Regenerate the commands, outputs and everything related.
 	target.resetPreRun()
+
+	// And print what was printed to the menu while it was not active.
+	target.replayMessages()
 }
 
 //
@@ -297,7 +359,7 @@ func (c *Console) SwitchMenu(menu string) {
 // below the line, and will not print the prompt. In any other case this function works normally.
 func (c *Console) TransientPrintf(msg string, args ...any) (n int, err error) {
 	if c.isExecuting.Load() {
-		return fmt.Printf(msg, args...)
+		return c.progress.printAbove(c, fmt.Sprintf(msg, args...))
 	}
 
 	newlineAfter := c.activeMenu().newlineAfter()
@@ -365,6 +427,9 @@ func (c *Console) setupShell() {
 	// General UI
 	cfg.Set("usage-hint-always", true)
 	cfg.Set("history-autosuggest", true)
+
+	// Ctrl-Z suspends the console, like while commands run.
+	c.bindSuspend()
 }
 
 func (c *Console) activeMenu() *Menu {
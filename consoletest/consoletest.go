@@ -0,0 +1,213 @@
+// Package consoletest provides a harness to test console applications end to
+// end, from outside the console package: lines or raw keystrokes are scripted
+// into a console, which runs them through its usual execution path, while the
+// harness captures what was printed, the errors, prompts and menu switches.
+//
+// Example:
+//
+//	func TestCommands(t *testing.T) {
+//		app := console.New("app")
+//		app.ActiveMenu().SetCommands(myCommands(app))
+//
+//		h := consoletest.New(t, app)
+//		h.Line("connect host").ExpectNoError().ExpectMenu("session")
+//		h.Line("info").ExpectOutput("host")
+//		h.Keys("inf\t\r").ExpectOutput("host") // Using completion
+//	}
+//
+// Output is captured by redirecting os.Stdout and os.Stderr while a line runs,
+// so that commands printing directly (with fmt.Print and the likes) are also
+// captured. Since these are process-wide, the harness serializes all runs,
+// and tests using it should not be run in parallel with tests printing.
+package consoletest
+
+import (
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reeflective/console"
+)
+
+// Harness drives a console application with scripted input.
+type Harness struct {
+	tb      testing.TB
+	console *console.Console
+	ctx     context.Context
+	results []Result
+}
+
+// New returns a harness driving the given console. Commands run with the
+// test context (canceled just before the test finishes), use WithContext
+// to use another one.
+func New(tb testing.TB, c *console.Console) *Harness {
+	tb.Helper()
+
+	return &Harness{
+		tb:      tb,
+		console: c,
+		ctx:     tb.Context(),
+	}
+}
+
+// WithContext sets the context with which commands are executed.
+func (h *Harness) WithContext(ctx context.Context) *Harness {
+	h.ctx = ctx
+	return h
+}
+
+// Console returns the console driven by the harness.
+func (h *Harness) Console() *console.Console {
+	return h.console
+}
+
+// Line runs an input line in the active menu, as if it had been entered at
+// the prompt: it is parsed, processed by the line hooks and executed. The
+// line is not read through the readline shell, so no key is interpreted.
+func (h *Harness) Line(input string) Result {
+	h.tb.Helper()
+
+	return h.run(input, func() error {
+		return h.console.RunScript(h.ctx, strings.NewReader(input))
+	})
+}
+
+// Lines runs each input line in turn, like Line, and returns their results.
+func (h *Harness) Lines(inputs ...string) []Result {
+	h.tb.Helper()
+
+	results := make([]Result, 0, len(inputs))
+
+	for _, input := range inputs {
+		results = append(results, h.Line(input))
+	}
+
+	return results
+}
+
+// Keys feeds raw keystrokes to the console readline shell and runs one
+// iteration of the console loop: the keys are interpreted by the shell
+// (completion with "\t", line editing, etc), and the accepted line, if
+// any, is executed. Interrupt keys (like Ctrl-C "\x03" or Ctrl-D "\x04")
+// are dispatched to the menu interrupt handlers.
+//
+// The keys must either accept the line (with "\r") or produce an interrupt,
+// otherwise the shell would block waiting for more input: if they do neither,
+// a carriage return is appended to them.
+func (h *Harness) Keys(keys string) Result {
+	h.tb.Helper()
+
+	if !strings.ContainsAny(keys, "\r\n\x03\x04") {
+		keys += "\r"
+	}
+
+	return h.run(keys, func() error {
+		h.console.Shell().Keys.Feed(false, []rune(keys)...)
+
+		return h.console.RunOnce(h.ctx)
+	})
+}
+
+// Results returns the results of all inputs run so far, in order.
+func (h *Harness) Results() []Result {
+	return h.results
+}
+
+// ExpectMenu fails the test if the active menu is not the named one.
+func (h *Harness) ExpectMenu(name string) *Harness {
+	h.tb.Helper()
+
+	if got := h.console.ActiveMenu().Name(); got != name {
+		h.tb.Errorf("active menu is %q, want %q", got, name)
+	}
+
+	return h
+}
+
+// run executes fn with outputs captured, and records its result.
+func (h *Harness) run(input string, fn func() error) Result {
+	h.tb.Helper()
+
+	res := Result{
+		tb:    h.tb,
+		Input: input,
+		From:  h.console.ActiveMenu().Name(),
+	}
+
+	start := time.Now()
+	res.Stdout, res.Stderr = capture(h.tb, func() { res.Err = fn() })
+	res.Duration = time.Since(start)
+
+	menu := h.console.ActiveMenu()
+	res.Menu = menu.Name()
+	res.Status = h.console.ExitStatus()
+
+	if prompt := menu.Prompt(); prompt.Primary != nil {
+		res.Prompt = prompt.Primary()
+	}
+
+	h.results = append(h.results, res)
+
+	return res
+}
+
+// outputs serializes runs, since they all redirect the process outputs.
+var outputs sync.Mutex
+
+// capture redirects os.Stdout and os.Stderr for the duration of fn,
+// and returns what was written to each of them.
+func capture(tb testing.TB, fn func()) (stdout, stderr string) {
+	tb.Helper()
+
+	outputs.Lock()
+	defer outputs.Unlock()
+
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		tb.Fatalf("consoletest: stdout pipe: %v", err)
+	}
+
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		tb.Fatalf("consoletest: stderr pipe: %v", err)
+	}
+
+	// Drain the pipes while fn runs, so that large
+	// outputs cannot fill them and block the command.
+	var outBuf, errBuf strings.Builder
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	go func() { defer wg.Done(); _, _ = io.Copy(&outBuf, outR) }()
+	go func() { defer wg.Done(); _, _ = io.Copy(&errBuf, errR) }()
+
+	origOut, origErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = outW, errW
+
+	func() {
+		defer func() { os.Stdout, os.Stderr = origOut, origErr }()
+		fn()
+	}()
+
+	_ = outW.Close()
+	_ = errW.Close()
+	wg.Wait()
+	_ = outR.Close()
+	_ = errR.Close()
+
+	return outBuf.String(), errBuf.String()
+}
+
+var ansi = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+// StripANSI removes all ANSI escape sequences from s, like colors and the
+// cursor movements produced by the shell when rendering prompts and input.
+func StripANSI(s string) string {
+	return ansi.ReplaceAllString(s, "")
+}
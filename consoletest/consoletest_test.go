@@ -0,0 +1,95 @@
+package consoletest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+type exitError struct{ code int }
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+func (e exitError) ExitCode() int { return e.code }
+
+func newTestConsole() *console.Console {
+	app := console.New("test")
+
+	app.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddCommand(&cobra.Command{
+			Use: "hello",
+			Run: func(cmd *cobra.Command, args []string) {
+				fmt.Println("hello world")
+			},
+		})
+		root.AddCommand(&cobra.Command{
+			Use:           "fail",
+			SilenceErrors: true,
+			SilenceUsage:  true,
+			RunE: func(*cobra.Command, []string) error {
+				return exitError{7}
+			},
+		})
+		root.AddCommand(&cobra.Command{
+			Use: "client",
+			Run: func(*cobra.Command, []string) { app.SwitchMenu("client") },
+		})
+
+		return root
+	})
+
+	client := app.NewMenu("client")
+	client.AddInterrupt(io.EOF, func(c *console.Console) { c.SwitchMenu("") })
+	client.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddCommand(&cobra.Command{
+			Use: "info",
+			Run: func(*cobra.Command, []string) { fmt.Println("client info") },
+		})
+
+		return root
+	})
+
+	return app
+}
+
+func TestHarnessLines(t *testing.T) {
+	h := New(t, newTestConsole())
+
+	h.Line("hello").ExpectNoError().ExpectOutput("hello world").ExpectStatus(0)
+	h.Line("fail").ExpectError("exit status 7").ExpectStatus(7).ExpectStderr("exit status 7")
+
+	res := h.Line("client").ExpectNoError().ExpectMenu("client").ExpectPrompt("[client]")
+	if !res.Switched() {
+		t.Fatal("switching menus was not recorded")
+	}
+
+	h.Line("info").ExpectOutput("client info")
+
+	if got := len(h.Results()); got != 4 {
+		t.Fatalf("recorded %d results, want 4", got)
+	}
+}
+
+func TestHarnessKeys(t *testing.T) {
+	h := New(t, newTestConsole())
+
+	// Completion, then accept the line.
+	h.Keys("hel\t\r").ExpectNoError().ExpectOutput("hello world")
+
+	// Interrupt handlers are dispatched.
+	h.Line("client").ExpectMenu("client")
+
+	res := h.Keys("\x04")
+	if !errors.Is(res.Err, io.EOF) {
+		t.Fatalf("Ctrl-D error = %v, want io.EOF", res.Err)
+	}
+
+	res.ExpectMenu("")
+	h.ExpectMenu("")
+}
@@ -0,0 +1,138 @@
+package consoletest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Result holds everything produced by running one input in the console.
+// Its Expect methods report failures on the test of the harness, and
+// return the result so that they can be chained.
+type Result struct {
+	tb testing.TB
+
+	Input    string        // Line or keys run in the console.
+	Stdout   string        // Everything written to os.Stdout (including shell renders for keys).
+	Stderr   string        // Everything written to os.Stderr (including the default error handler).
+	Err      error         // Error passed to the menu error handler, or interrupt error.
+	Status   int           // Exit status of the last command (see console.Console.ExitStatus).
+	From     string        // Name of the active menu before the input was run.
+	Menu     string        // Name of the active menu after the input was run.
+	Prompt   string        // Primary prompt of the active menu after the input was run.
+	Duration time.Duration // Time taken to run the input.
+}
+
+// Switched returns true if the input has changed the active menu.
+func (r Result) Switched() bool {
+	return r.From != r.Menu
+}
+
+// Output returns the standard output, stripped from ANSI escape sequences.
+func (r Result) Output() string {
+	return StripANSI(r.Stdout)
+}
+
+// ExpectOutput fails the test if the standard output does not contain
+// each of the given strings. Output is compared without ANSI sequences.
+func (r Result) ExpectOutput(contains ...string) Result {
+	r.tb.Helper()
+
+	for _, want := range contains {
+		if !strings.Contains(r.Output(), want) {
+			r.tb.Errorf("%q: stdout does not contain %q:\n%s", r.Input, want, r.Output())
+		}
+	}
+
+	return r
+}
+
+// ExpectStderr fails the test if the standard error does not contain
+// each of the given strings. Output is compared without ANSI sequences.
+func (r Result) ExpectStderr(contains ...string) Result {
+	r.tb.Helper()
+
+	stderr := StripANSI(r.Stderr)
+
+	for _, want := range contains {
+		if !strings.Contains(stderr, want) {
+			r.tb.Errorf("%q: stderr does not contain %q:\n%s", r.Input, want, stderr)
+		}
+	}
+
+	return r
+}
+
+// ExpectError fails the test if running the input did not produce an
+// error, or if the error message does not contain the given string.
+func (r Result) ExpectError(contains string) Result {
+	r.tb.Helper()
+
+	switch {
+	case r.Err == nil:
+		r.tb.Errorf("%q: expected an error, got none", r.Input)
+	case !strings.Contains(r.Err.Error(), contains):
+		r.tb.Errorf("%q: error %q does not contain %q", r.Input, r.Err, contains)
+	}
+
+	return r
+}
+
+// ExpectErrorAs fails the test if the error does not match target,
+// like errors.As (target is a pointer to the desired error type).
+func (r Result) ExpectErrorAs(target any) Result {
+	r.tb.Helper()
+
+	if r.Err == nil || !errors.As(r.Err, target) {
+		r.tb.Errorf("%q: error %v does not match %T", r.Input, r.Err, target)
+	}
+
+	return r
+}
+
+// ExpectNoError fails the test if running the input produced an error.
+func (r Result) ExpectNoError() Result {
+	r.tb.Helper()
+
+	if r.Err != nil {
+		r.tb.Errorf("%q: unexpected error: %v", r.Input, r.Err)
+	}
+
+	return r
+}
+
+// ExpectStatus fails the test if the exit status is not the given one.
+func (r Result) ExpectStatus(status int) Result {
+	r.tb.Helper()
+
+	if r.Status != status {
+		r.tb.Errorf("%q: exit status is %d, want %d (error: %v)", r.Input, r.Status, status, r.Err)
+	}
+
+	return r
+}
+
+// ExpectMenu fails the test if the active menu after
+// running the input is not the one with the given name.
+func (r Result) ExpectMenu(name string) Result {
+	r.tb.Helper()
+
+	if r.Menu != name {
+		r.tb.Errorf("%q: active menu is %q, want %q", r.Input, r.Menu, name)
+	}
+
+	return r
+}
+
+// ExpectPrompt fails the test if the primary prompt of the active menu
+// does not contain the given string (compared without ANSI sequences).
+func (r Result) ExpectPrompt(contains string) Result {
+	r.tb.Helper()
+
+	if prompt := StripANSI(r.Prompt); !strings.Contains(prompt, contains) {
+		r.tb.Errorf("%q: prompt %q does not contain %q", r.Input, prompt, contains)
+	}
+
+	return r
+}
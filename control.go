@@ -0,0 +1,332 @@
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/reeflective/console/internal/command"
+	"github.com/reeflective/console/internal/jsonrpc"
+)
+
+// ServeControl accepts connections on the listener (usually a Unix socket) and
+// serves a JSON-RPC 2.0 control endpoint on each of them, so that programs like
+// editor integrations or test automation can drive the console without going
+// through its readline interface. It returns when the context is canceled (nil)
+// or the listener fails, after all connections are closed.
+//
+// Messages are newline-delimited JSON objects. The available methods are:
+//
+//	console.menus     {}                         -> [{"name", "active"}]
+//	console.commands  {"menu"}                   -> [{"path", "short", "aliases", "hidden", "filters", "flags"}]
+//	console.switch    {"menu"}                   -> {"name", "active"}
+//	console.run       {"menu", "line"}           -> {"stdout", "stderr", "error", "status", "duration"}
+//	console.complete  {"menu", "line", "cursor"} -> {"values", "usage", "messages", "prefix", "noSpace"}
+//
+// The menu parameter is optional, and defaults to the active menu. Command lines
+// are run like in sessions (see Session), on new command trees of their menu,
+// without affecting the console (its command trees, exit status, etc), so menus
+// need a command generator. Like with Menu.Exec, only the output written by
+// commands through cobra is captured, and a failing command is not a JSON-RPC
+// error: its error message and exit status are part of the result. Requests
+// from all connections are processed one at a time.
+//
+// Example, with the socket path given by the user:
+//
+//	listener, err := net.Listen("unix", socketPath)
+//	if err != nil {
+//		return err
+//	}
+//
+//	go app.ServeControl(ctx, listener)
+//	app.Start()
+func (c *Console) ServeControl(ctx context.Context, listener net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(ctx, func() { _ = listener.Close() })
+	defer stop()
+
+	server := c.newControlServer()
+
+	var conns sync.WaitGroup
+	defer conns.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		conns.Add(1)
+
+		go func() {
+			defer conns.Done()
+			defer conn.Close()
+
+			closed := context.AfterFunc(ctx, func() { _ = conn.Close() })
+			defer closed()
+
+			_ = server.Serve(ctx, conn, conn)
+		}()
+	}
+}
+
+type controlMenu struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+}
+
+type controlCommand struct {
+	Path    string        `json:"path"`
+	Short   string        `json:"short,omitempty"`
+	Aliases []string      `json:"aliases,omitempty"`
+	Hidden  bool          `json:"hidden,omitempty"`
+	Filters []string      `json:"filters,omitempty"`
+	Flags   []controlFlag `json:"flags,omitempty"`
+}
+
+type controlFlag struct {
+	Name      string `json:"name"`
+	Shorthand string `json:"shorthand,omitempty"`
+	Type      string `json:"type"`
+	Default   string `json:"default,omitempty"`
+	Usage     string `json:"usage,omitempty"`
+}
+
+type controlResult struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	Error    string `json:"error,omitempty"`
+	Status   int    `json:"status"`
+	Duration int64  `json:"duration"` // In milliseconds.
+}
+
+type controlCompletion struct {
+	Value       string `json:"value"`
+	Display     string `json:"display,omitempty"`
+	Description string `json:"description,omitempty"`
+	Tag         string `json:"tag,omitempty"`
+}
+
+type controlCompletions struct {
+	Values   []controlCompletion `json:"values"`
+	Usage    string              `json:"usage,omitempty"`
+	Messages []string            `json:"messages,omitempty"`
+	Prefix   string              `json:"prefix,omitempty"`
+	NoSpace  string              `json:"noSpace,omitempty"`
+}
+
+// newControlServer returns a JSON-RPC server with all control methods.
+func (c *Console) newControlServer() *jsonrpc.Server {
+	server := jsonrpc.NewServer()
+
+	// Serialize requests, so that they take effect in the order they are received.
+	var mutex sync.Mutex
+
+	handle := func(method string, handler jsonrpc.Handler) {
+		server.Handle(method, func(ctx context.Context, params json.RawMessage) (any, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			return handler(ctx, params)
+		})
+	}
+
+	handle("console.menus", c.controlMenus)
+	handle("console.commands", c.controlCommands)
+	handle("console.switch", c.controlSwitch)
+	handle("console.run", c.controlRun)
+	handle("console.complete", c.controlComplete)
+
+	return server
+}
+
+func (c *Console) controlMenus(_ context.Context, _ json.RawMessage) (any, error) {
+	active := c.activeMenu()
+	menus := []controlMenu{}
+
+	for _, menu := range c.Menus() {
+		menus = append(menus, controlMenu{Name: menu.name, Active: menu == active})
+	}
+
+	return menus, nil
+}
+
+func (c *Console) controlCommands(_ context.Context, params json.RawMessage) (any, error) {
+	var req struct {
+		Menu *string `json:"menu"`
+	}
+
+	menu, err := c.controlMenu(params, &req, &req.Menu)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := menu.activeFilters()
+	commands := []controlCommand{}
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			if sub.Name() == "_carapace" {
+				continue
+			}
+
+			commands = append(commands, newControlCommand(sub, filters))
+			walk(sub)
+		}
+	}
+
+	walk(menu.newCommands(filters))
+
+	return commands, nil
+}
+
+func (c *Console) controlSwitch(_ context.Context, params json.RawMessage) (any, error) {
+	var req struct {
+		Menu *string `json:"menu"`
+	}
+
+	menu, err := c.controlMenu(params, &req, &req.Menu)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SwitchMenu(menu.name)
+
+	return controlMenu{Name: c.activeMenu().name, Active: true}, nil
+}
+
+func (c *Console) controlRun(ctx context.Context, params json.RawMessage) (any, error) {
+	var req struct {
+		Menu *string `json:"menu"`
+		Line string  `json:"line"`
+	}
+
+	menu, err := c.controlMenu(params, &req, &req.Menu)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := menu.splitLine(req.Line)
+	if err != nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%s", err)
+	}
+
+	if len(args) == 0 {
+		return controlResult{}, nil
+	}
+
+	// The user might be using the console meanwhile:
+	// run the command without touching its state.
+	res, err := menu.execDetached(ctx, args)
+	if err != nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%s", err)
+	}
+
+	result := controlResult{
+		Stdout:   string(res.Stdout),
+		Stderr:   string(res.Stderr),
+		Status:   res.Status,
+		Duration: res.Duration.Milliseconds(),
+	}
+
+	if res.Err != nil {
+		result.Error = res.Err.Error()
+	}
+
+	return result, nil
+}
+
+func (c *Console) controlComplete(_ context.Context, params json.RawMessage) (any, error) {
+	var req struct {
+		Menu   *string `json:"menu"`
+		Line   string  `json:"line"`
+		Cursor *int    `json:"cursor"`
+	}
+
+	menu, err := c.controlMenu(params, &req, &req.Menu)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := -1
+	if req.Cursor != nil {
+		cursor = *req.Cursor
+	}
+
+	comps := menu.Complete(req.Line, cursor)
+
+	result := controlCompletions{
+		Values:   make([]controlCompletion, 0, len(comps.Values)),
+		Usage:    comps.Usage,
+		Messages: comps.Messages,
+		Prefix:   comps.Prefix,
+		NoSpace:  comps.NoSpace,
+	}
+
+	for _, comp := range comps.Values {
+		result.Values = append(result.Values, controlCompletion{
+			Value:       comp.Value,
+			Display:     comp.Display,
+			Description: comp.Description,
+			Tag:         comp.Tag,
+		})
+	}
+
+	return result, nil
+}
+
+// controlMenu decodes the request parameters into req, and returns the menu
+// they name in their menu field, or the active menu if it is not specified.
+func (c *Console) controlMenu(params json.RawMessage, req any, name **string) (*Menu, error) {
+	if err := jsonrpc.Decode(params, req); err != nil {
+		return nil, err
+	}
+
+	if *name == nil {
+		return c.activeMenu(), nil
+	}
+
+	menu := c.Menu(**name)
+	if menu == nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "no menu named %q", **name)
+	}
+
+	return menu, nil
+}
+
+func newControlCommand(cmd *cobra.Command, filters []string) controlCommand {
+	info := controlCommand{
+		Path:    commandPath(cmd),
+		Short:   cmd.Short,
+		Aliases: cmd.Aliases,
+		Hidden:  cmd.Hidden,
+		Filters: command.ActiveFilters(cmd, filters),
+	}
+
+	cmd.NonInheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+
+		info.Flags = append(info.Flags, controlFlag{
+			Name:      flag.Name,
+			Shorthand: flag.Shorthand,
+			Type:      flag.Value.Type(),
+			Default:   flag.DefValue,
+			Usage:     flag.Usage,
+		})
+	})
+
+	return info
+}
@@ -0,0 +1,165 @@
+package console
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// controlClient sends JSON-RPC requests to a control endpoint.
+type controlClient struct {
+	t       *testing.T
+	conn    net.Conn
+	scanner *bufio.Scanner
+	id      int
+}
+
+func (cl *controlClient) call(method string, params any, result any) *struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+} {
+	cl.t.Helper()
+
+	cl.id++
+
+	req, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": cl.id, "method": method, "params": params})
+	if _, err := cl.conn.Write(append(req, '\n')); err != nil {
+		cl.t.Fatalf("write %s: %v", method, err)
+	}
+
+	_ = cl.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	if !cl.scanner.Scan() {
+		cl.t.Fatalf("read %s: %v", method, cl.scanner.Err())
+	}
+
+	var resp struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	if err := json.Unmarshal(cl.scanner.Bytes(), &resp); err != nil {
+		cl.t.Fatalf("decode %s response %q: %v", method, cl.scanner.Text(), err)
+	}
+
+	if resp.ID != cl.id {
+		cl.t.Fatalf("%s response id = %d, want %d", method, resp.ID, cl.id)
+	}
+
+	if resp.Error == nil && result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			cl.t.Fatalf("decode %s result %q: %v", method, resp.Result, err)
+		}
+	}
+
+	return resp.Error
+}
+
+func TestServeControl(t *testing.T) {
+	c := newSessionConsole()
+	c.HideCommands("windows")
+
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "control.sock"))
+	if err != nil {
+		t.Skipf("cannot listen on a unix socket: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+
+	go func() { served <- c.ServeControl(ctx, listener) }()
+
+	conn, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &controlClient{t: t, conn: conn, scanner: bufio.NewScanner(conn)}
+
+	// Menus
+	var menus []controlMenu
+	client.call("console.menus", nil, &menus)
+
+	if len(menus) != 2 || menus[0].Name != "" || !menus[0].Active || menus[1].Name != "client" {
+		t.Fatalf("menus = %+v, want the default (active) and client menus", menus)
+	}
+
+	// Commands, with their filtered state.
+	var commands []controlCommand
+	client.call("console.commands", map[string]any{}, &commands)
+
+	found := map[string]controlCommand{}
+	for _, cmd := range commands {
+		found[cmd.Path] = cmd
+	}
+
+	if win := found["win"]; !win.Hidden || len(win.Filters) != 1 || win.Filters[0] != "windows" {
+		t.Fatalf("win command = %+v, want hidden by the windows filter", win)
+	}
+	if _, ok := found["client"]; !ok {
+		t.Fatalf("commands = %+v, want the client command", commands)
+	}
+
+	// Running commands in a menu other than the active one.
+	var res controlResult
+	client.call("console.run", map[string]any{"menu": "client", "line": "info"}, &res)
+
+	if res.Stdout != "client info\n" || res.Status != 0 || res.Error != "" {
+		t.Fatalf("run result = %+v, want client info", res)
+	}
+
+	client.call("console.run", map[string]any{"line": "win"}, &res)
+
+	if res.Status != 1 || !strings.Contains(res.Error, "hidden by filter expression") {
+		t.Fatalf("filtered run result = %+v, want a filter error", res)
+	}
+
+	// The console state is not affected by commands run through the endpoint.
+	if status := c.ExitStatus(); status != 0 {
+		t.Fatalf("console exit status = %d, want 0", status)
+	}
+
+	// Completions
+	var comps controlCompletions
+	client.call("console.complete", map[string]any{"menu": "client", "line": "in"}, &comps)
+
+	if len(comps.Values) != 1 || strings.TrimSpace(comps.Values[0].Value) != "info" {
+		t.Fatalf("completions = %+v, want info", comps)
+	}
+
+	// Switching menus
+	var menu controlMenu
+	client.call("console.switch", map[string]any{"menu": "client"}, &menu)
+
+	if menu.Name != "client" || c.ActiveMenu().Name() != "client" {
+		t.Fatalf("switch result = %+v, active menu %q, want client", menu, c.ActiveMenu().Name())
+	}
+
+	// Errors
+	if rpcErr := client.call("console.switch", map[string]any{"menu": "none"}, nil); rpcErr == nil || rpcErr.Code != -32602 {
+		t.Fatalf("switch to an unknown menu error = %+v, want invalid params", rpcErr)
+	}
+	if rpcErr := client.call("console.unknown", nil, nil); rpcErr == nil || rpcErr.Code != -32601 {
+		t.Fatalf("unknown method error = %+v, want method not found", rpcErr)
+	}
+
+	cancel()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("ServeControl returned %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeControl did not return after its context was canceled")
+	}
+}
@@ -0,0 +1,163 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/reeflective/readline"
+
+	"github.com/reeflective/console/internal/ui"
+)
+
+// ErrNotInteractive is returned by dialogs (like Console.Confirm) when they are
+// used by a command that is not run from the console prompt, like commands run
+// with RunScript, RunCommandArgs, Menu.Exec or in sessions: nobody can answer them.
+var ErrNotInteractive = errors.New("no user to answer the dialog")
+
+// interactiveKey is the context key marking executions of command lines
+// read at the console prompt with a true value. Other executions are not
+// marked, or are marked with a false value.
+type interactiveKey struct{}
+
+// interactive returns true if the context is the one
+// of a command line read at the console prompt.
+func interactive(ctx context.Context) bool {
+	value, found := ctx.Value(interactiveKey{}).(bool)
+
+	return found && value
+}
+
+// Confirm asks a yes/no question to the user, no being the default answer.
+//
+// Dialogs read their answer with the console shell, like command lines are,
+// but the menu prompt, history, highlighting and completions are replaced
+// with those of the dialog while the user answers. Answers are never written
+// to the menu histories. Dialogs can only be used by commands run by the
+// console, with their context (cmd.Context()), or while the console is not
+// reading a command line (in interrupt handlers, for instance). They return
+// ErrNotInteractive if the command is not run from the prompt, and the shell
+// error if the user interrupts them (Ctrl-C or Ctrl-D).
+func (c *Console) Confirm(ctx context.Context, question string) (bool, error) {
+	answer, err := c.readDialog(ctx, dialog{prompt: question + " [y/N] "})
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Password asks the user for a secret, which is not displayed while typed.
+// See Console.Confirm for how dialogs work.
+func (c *Console) Password(ctx context.Context, prompt string) (string, error) {
+	return c.readDialog(ctx, dialog{prompt: prompt, secret: true})
+}
+
+// Select asks the user to choose one of the options, which are proposed as
+// completions (with Tab), and returns it. The question is asked again until
+// the answer is one of them. See Console.Confirm for how dialogs work.
+func (c *Console) Select(ctx context.Context, prompt string, options []string) (string, error) {
+	completer := func([]rune, int) readline.Completions {
+		return readline.CompleteValues(options...).NoSort()
+	}
+
+	for {
+		answer, err := c.readDialog(ctx, dialog{prompt: prompt, completer: completer})
+		if err != nil {
+			return "", err
+		}
+
+		if answer = strings.TrimSpace(answer); slices.Contains(options, answer) {
+			return answer, nil
+		}
+	}
+}
+
+// Input asks the user for a value, returning the default one if the answer is
+// empty. If not nil, the completer provides completions for the answer, like
+// the shell Completer does for command lines. See Console.Confirm for how
+// dialogs work.
+func (c *Console) Input(ctx context.Context, prompt, defaultValue string, completer func(line []rune, cursor int) readline.Completions) (string, error) {
+	if defaultValue != "" {
+		prompt = strings.TrimRight(prompt, " ") + " [" + defaultValue + "] "
+	}
+
+	answer, err := c.readDialog(ctx, dialog{prompt: prompt, completer: completer})
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(answer) == "" {
+		return defaultValue, nil
+	}
+
+	return answer, nil
+}
+
+// dialog is a one-off question asked to the user through the console shell.
+type dialog struct {
+	prompt    string                                             // Prompt displayed before the answer.
+	secret    bool                                               // Don't display the answer.
+	completer func(line []rune, cursor int) readline.Completions // Completions for the answer (none if nil).
+}
+
+// readDialog reads an answer to a dialog with the console shell, replacing
+// the menu prompt, history, highlighting and completions with those of the
+// dialog, and restoring them once the answer is read.
+func (c *Console) readDialog(ctx context.Context, d dialog) (string, error) {
+	// Commands can only ask questions when run from the prompt,
+	// but dialogs can be used outside commands too, when the
+	// console does not read input (interrupt handlers, etc).
+	if !interactive(ctx) && ctx.Value(runningKey{}) != nil {
+		return "", ErrNotInteractive
+	}
+
+	c.dialogs.Lock()
+	defer c.dialogs.Unlock()
+
+	shell := c.shell
+	highlighter, completer, multiline := shell.SyntaxHighlighter, shell.Completer, shell.AcceptMultiline
+
+	defer func() {
+		shell.SyntaxHighlighter, shell.Completer, shell.AcceptMultiline = highlighter, completer, multiline
+
+		ui.BindPrompt((*ui.Prompt)(c.activeMenu().Prompt()), shell)
+		c.loadActiveHistories()
+	}()
+
+	shell.Prompt.Primary(func() string { return d.prompt })
+	shell.Prompt.Right(nil)
+	shell.Prompt.Secondary(nil)
+	shell.Prompt.Transient(nil)
+	shell.Prompt.Tooltip(func(string) string { return "" })
+
+	shell.History.Delete()
+	shell.History.Add("dialog", readline.NewInMemoryHistory())
+
+	shell.SyntaxHighlighter = nil
+	shell.Completer = d.completer
+	shell.AcceptMultiline = nil
+
+	// Secrets are displayed as blanks, so that the
+	// cursor still moves as the user would expect.
+	if d.secret {
+		shell.SyntaxHighlighter = func(line []rune) string {
+			return strings.Repeat(" ", len(line))
+		}
+	}
+
+	for {
+		answer, err := shell.Readline()
+		if !errors.Is(err, errSuspend) {
+			return answer, err
+		}
+
+		c.suspend()
+	}
+}
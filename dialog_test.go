@@ -0,0 +1,83 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/spf13/cobra"
+)
+
+func TestDialogs(t *testing.T) {
+	c := New("test")
+
+	var answers []string
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{
+			Use: "login",
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				ctx := cmd.Context()
+
+				host, err := c.Select(ctx, "host: ", []string{"alpha", "beta"})
+				if err != nil {
+					return err
+				}
+
+				user, err := c.Input(ctx, "user:", "root", func([]rune, int) readline.Completions {
+					return readline.CompleteValues("admin")
+				})
+				if err != nil {
+					return err
+				}
+
+				password, err := c.Password(ctx, "password: ")
+				if err != nil {
+					return err
+				}
+
+				answers = append(answers, host, user, password)
+
+				return nil
+			},
+		})
+
+		return root
+	})
+
+	// An invalid choice is asked again, the user is completed,
+	// and the password is read like any other answer.
+	c.Shell().Keys.Feed(false, []rune("login\rgamma\rbeta\rad\t\rs3cr3t\r")...)
+
+	if err := c.RunOnce(context.Background()); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	if want := []string{"beta", "admin", "s3cr3t"}; len(answers) != 3 || answers[0] != want[0] ||
+		answers[1] != want[1] || answers[2] != want[2] {
+		t.Fatalf("answers = %q, want %q", answers, want)
+	}
+
+	hist := c.ActiveMenu().histories[c.ActiveMenu().historyNames[0]]
+	for i := range hist.Len() {
+		if line, _ := hist.GetLine(i); line != "login" {
+			t.Fatalf("history has the answer %q", line)
+		}
+	}
+
+	// The default value is used for empty answers.
+	answers = nil
+
+	c.Shell().Keys.Feed(false, []rune("login\ralpha\r\rpass\r")...)
+
+	if err := c.RunOnce(context.Background()); err != nil || len(answers) != 3 || answers[1] != "root" {
+		t.Fatalf("login = %v, answers %q, want the default user", err, answers)
+	}
+
+	// Nobody can answer dialogs of commands run with Exec.
+	if _, err := c.ActiveMenu().Exec(context.Background(), "login"); !errors.Is(err, ErrNotInteractive) {
+		t.Fatalf("Exec login = %v, want ErrNotInteractive", err)
+	}
+}
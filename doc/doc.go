@@ -0,0 +1,104 @@
+// Package doc generates Markdown and man page documentation for all the
+// menus of a console, like cobra/doc does for a single command tree.
+//
+// Each menu is documented as a whole (one file per menu), since menu roots
+// are usually anonymous commands: the documentation lists each command of
+// the menu with its usage, aliases, flags and examples, and the filters hiding
+// it or its flags (as declared with console.CommandFilterKey annotations).
+//
+// Example, generating the documentation of all menus in ./docs:
+//
+//	if err := doc.GenMarkdownTree(app, "./docs"); err != nil {
+//		return err
+//	}
+//
+//	header := &doc.ManHeader{Section: "1", Source: "app " + version}
+//	if err := doc.GenManTree(app, header, "./man"); err != nil {
+//		return err
+//	}
+package doc
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/reeflective/console"
+)
+
+// BaseName returns the name of the documentation of a menu, without
+// extension: the application name for the default menu, followed by
+// the menu name (with a dash) for all others.
+func BaseName(c *console.Console, menu *console.Menu) string {
+	name := c.Name()
+	if name == "" {
+		name = "console"
+	}
+
+	if menu.Name() != "" {
+		name += "-" + menu.Name()
+	}
+
+	return strings.ReplaceAll(strings.ToLower(name), " ", "_")
+}
+
+// commands returns all documented commands of a menu, in order:
+// hidden commands, and internal ones (help, completion) are omitted.
+func commands(menu *console.Menu) []*cobra.Command {
+	var cmds []*cobra.Command
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			if !sub.IsAvailableCommand() || sub.IsAdditionalHelpTopicCommand() {
+				continue
+			}
+
+			cmds = append(cmds, sub)
+			walk(sub)
+		}
+	}
+
+	walk(menu.CommandTree())
+
+	return cmds
+}
+
+// path returns the command path of cmd, without its (usually anonymous) root.
+func path(cmd *cobra.Command) string {
+	return strings.TrimSpace(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()))
+}
+
+// usage returns the usage line of cmd, without its root.
+func usage(cmd *cobra.Command) string {
+	return strings.TrimSpace(strings.TrimPrefix(cmd.UseLine(), cmd.Root().Name()))
+}
+
+// filterExpression returns the filter expression declared on cmd with console.CommandFilterKey.
+func filterExpression(cmd *cobra.Command) string {
+	return strings.TrimSpace(cmd.Annotations[console.CommandFilterKey])
+}
+
+// flagFilterExpression returns the filter expression declared on a flag
+// with console.CommandFilterKey, its values being or-ed together.
+func flagFilterExpression(flag *pflag.Flag) string {
+	return strings.TrimSpace(strings.Join(flag.Annotations[console.CommandFilterKey], ","))
+}
+
+// filteredFlags returns the documented flags of cmd (its own and inherited
+// ones) declaring a filter expression.
+func filteredFlags(cmd *cobra.Command) []*pflag.Flag {
+	var flags []*pflag.Flag
+
+	visit := func(flag *pflag.Flag) {
+		if !flag.Hidden && flag.Deprecated == "" && flagFilterExpression(flag) != "" {
+			flags = append(flags, flag)
+		}
+	}
+
+	cmd.NonInheritedFlags().VisitAll(visit)
+	cmd.InheritedFlags().VisitAll(visit)
+
+	return flags
+}
@@ -0,0 +1,182 @@
+package doc
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+func newConsole() *console.Console {
+	app := console.New("App")
+
+	app.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.PersistentFlags().Bool("debug", false, "print debug logs")
+
+		connect := &cobra.Command{
+			Use:         "connect <host>",
+			Short:       "Connect to a host",
+			Long:        "Connect to a host.\n.Dots and back\\slashes are escaped.",
+			Aliases:     []string{"c", "conn"},
+			Example:     "connect --port 2222 example.com",
+			Annotations: map[string]string{console.CommandFilterKey: "offline,windows"},
+			Run:         func(*cobra.Command, []string) {},
+		}
+		connect.Flags().IntP("port", "p", 22, "port to use")
+		connect.Flags().String("registry", "", "registry key to read")
+		_ = connect.Flags().SetAnnotation("registry", console.CommandFilterKey, []string{"windows"})
+
+		root.AddCommand(connect,
+			&cobra.Command{Use: "secret", Hidden: true, Run: func(*cobra.Command, []string) {}},
+		)
+
+		return root
+	})
+
+	app.NewMenu("client").SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		group := &cobra.Command{Use: "files", Short: "Manage files"}
+		group.AddCommand(
+			&cobra.Command{Use: "ls [path]", Short: "List files", Run: func(*cobra.Command, []string) {}},
+			&cobra.Command{
+				Use:         "sync",
+				Short:       "Sync files",
+				Annotations: map[string]string{console.CommandFilterKey: "!windows"},
+				Run:         func(*cobra.Command, []string) {},
+			},
+		)
+		root.AddCommand(group)
+
+		return root
+	})
+
+	app.HideCommands("offline")
+
+	return app
+}
+
+func TestGenMarkdownTree(t *testing.T) {
+	app := newConsole()
+	dir := t.TempDir()
+
+	if err := GenMarkdownTree(app, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	main, err := os.ReadFile(filepath.Join(dir, "app.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"# App\n",
+		"* [connect](#connect) - Connect to a host",
+		"## connect\n",
+		"```\nconnect <host> [flags]\n```",
+		"**Aliases:** c, conn",
+		"**Hidden when filters match:** `offline,windows`",
+		"-p, --port int          port to use (default 22)",
+		"### Options inherited from parent commands",
+		"**Options hidden when filters match:**\n\n* `--registry`: `windows`\n",
+		"connect --port 2222 example.com",
+	} {
+		if !strings.Contains(string(main), want) {
+			t.Errorf("app.md does not contain %q:\n%s", want, main)
+		}
+	}
+
+	if strings.Contains(string(main), "secret") {
+		t.Errorf("app.md documents a hidden command:\n%s", main)
+	}
+
+	client, err := os.ReadFile(filepath.Join(dir, "app-client.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"# App client\n", "Commands of the `client` menu.", "## files\n", "## files ls\n", "files ls [path]", "## files sync\n"} {
+		if !strings.Contains(string(client), want) {
+			t.Errorf("app-client.md does not contain %q:\n%s", want, client)
+		}
+	}
+}
+
+func TestGenMan(t *testing.T) {
+	app := newConsole()
+	date := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	header := &ManHeader{Date: &date, Source: "App 1.0", Manual: `App "Back\slash" Manual`}
+	if err := GenMan(app.Menu(""), "app", header, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	page := buf.String()
+
+	for _, want := range []string{
+		`.TH "APP" "1" "Oct 2026" "App 1.0" "App \(dqBack\eslash\(dq Manual"`,
+		".SS connect\n",
+		"\\fBconnect <host> [flags]\\fP",
+		"\\&.Dots and back\\eslashes are escaped.",
+		"Aliases: c, conn",
+		"Hidden when filters match: offline,windows",
+		"\\fB\\-p\\fP, \\fB\\-\\-port\\fP=22\nport to use",
+		"\\fB\\-\\-debug\\fP\nprint debug logs",
+		"\\fB\\-\\-registry\\fP=\nregistry key to read\nHidden when filters match: windows\n",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("man page does not contain %q:\n%s", want, page)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := GenManTree(app, &ManHeader{Section: "7"}, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"app.7", "app-client.7"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("man page %s was not generated: %v", name, err)
+		}
+	}
+}
+
+func TestGenWithoutGenerator(t *testing.T) {
+	app := console.New("App")
+	menu := app.ActiveMenu()
+
+	root := &cobra.Command{}
+	root.AddCommand(
+		&cobra.Command{Use: "noop", Run: func(*cobra.Command, []string) {}},
+		&cobra.Command{
+			Use:         "connect",
+			Annotations: map[string]string{console.CommandFilterKey: "offline"},
+			Run:         func(*cobra.Command, []string) {},
+		},
+	)
+	menu.Command = root
+
+	// Running a command hides the filtered ones in the menu tree.
+	app.HideCommands("offline")
+
+	if err := menu.RunCommandArgs(context.Background(), []string{"noop"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := GenMarkdown(menu, "App", &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "## connect\n") {
+		t.Errorf("filtered command is not documented:\n%s", out.String())
+	}
+}
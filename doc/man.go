@@ -0,0 +1,169 @@
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/reeflective/console"
+)
+
+// ManHeader holds the fields of the man page header (.TH).
+type ManHeader struct {
+	Title   string     // Defaults to the upper-cased page name.
+	Section string     // Defaults to "1".
+	Date    *time.Time // Defaults to the current date.
+	Source  string     // Source of the page, like the application name and version.
+	Manual  string     // Title of the manual the page belongs to.
+}
+
+// GenMan writes the man page (in roff) of all the commands of a menu to w.
+// The name is the page name (see BaseName), and the header may be nil.
+func GenMan(menu *console.Menu, name string, header *ManHeader, w io.Writer) error {
+	if header == nil {
+		header = &ManHeader{}
+	}
+
+	title := header.Title
+	if title == "" {
+		title = strings.ToUpper(name)
+	}
+
+	section := header.Section
+	if section == "" {
+		section = "1"
+	}
+
+	date := time.Now()
+	if header.Date != nil {
+		date = *header.Date
+	}
+
+	buf := new(bytes.Buffer)
+
+	fmt.Fprintf(buf, ".TH %s %s %s %s %s\n", roffQuote(title), roffQuote(section),
+		roffQuote(date.Format("Jan 2006")), roffQuote(header.Source), roffQuote(header.Manual))
+	fmt.Fprintf(buf, ".SH NAME\n%s", roff(name))
+
+	if menu.Name() != "" {
+		fmt.Fprintf(buf, " \\- commands of the %s menu", roff(menu.Name()))
+	}
+
+	buf.WriteString("\n.SH COMMANDS\n")
+
+	for _, cmd := range commands(menu) {
+		genManCommand(buf, cmd)
+	}
+
+	_, err := buf.WriteTo(w)
+
+	return err
+}
+
+// GenManTree writes the man page of each console menu to its own file
+// in dir, named after BaseName with the header section as extension.
+func GenManTree(c *console.Console, header *ManHeader, dir string) error {
+	section := "1"
+	if header != nil && header.Section != "" {
+		section = header.Section
+	}
+
+	for _, menu := range c.Menus() {
+		name := BaseName(c, menu)
+
+		if err := genFile(filepath.Join(dir, name+"."+section), func(w io.Writer) error {
+			return GenMan(menu, name, header, w)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func genManCommand(buf *bytes.Buffer, cmd *cobra.Command) {
+	fmt.Fprintf(buf, ".SS %s\n", roff(path(cmd)))
+
+	if cmd.Runnable() {
+		fmt.Fprintf(buf, ".PP\n\\fB%s\\fP\n", roff(usage(cmd)))
+	}
+
+	if cmd.Long != "" {
+		fmt.Fprintf(buf, ".PP\n%s\n", roff(cmd.Long))
+	} else if cmd.Short != "" {
+		fmt.Fprintf(buf, ".PP\n%s\n", roff(cmd.Short))
+	}
+
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(buf, ".PP\nAliases: %s\n", roff(strings.Join(cmd.Aliases, ", ")))
+	}
+
+	if expr := filterExpression(cmd); expr != "" {
+		fmt.Fprintf(buf, ".PP\nHidden when filters match: %s\n", roff(expr))
+	}
+
+	genManFlags(buf, cmd.NonInheritedFlags())
+	genManFlags(buf, cmd.InheritedFlags())
+
+	if cmd.Example != "" {
+		fmt.Fprintf(buf, ".PP\nExamples:\n.PP\n.RS\n.nf\n%s\n.fi\n.RE\n", roff(cmd.Example))
+	}
+}
+
+func genManFlags(buf *bytes.Buffer, flags *pflag.FlagSet) {
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || flag.Deprecated != "" {
+			return
+		}
+
+		buf.WriteString(".TP\n")
+
+		if flag.Shorthand != "" && flag.ShorthandDeprecated == "" {
+			fmt.Fprintf(buf, "\\fB\\-%s\\fP, ", roff(flag.Shorthand))
+		}
+
+		fmt.Fprintf(buf, "\\fB\\-\\-%s\\fP", roff(flag.Name))
+
+		if flag.Value.Type() != "bool" {
+			fmt.Fprintf(buf, "=%s", roff(flag.DefValue))
+		}
+
+		fmt.Fprintf(buf, "\n%s\n", roff(flag.Usage))
+
+		if expr := flagFilterExpression(flag); expr != "" {
+			fmt.Fprintf(buf, "Hidden when filters match: %s\n", roff(expr))
+		}
+	})
+}
+
+// roff escapes text for roff: backslashes and dashes are escaped,
+// and lines starting with a control character are protected.
+func roff(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, "-", `\-`)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// roffQuote quotes text as an argument of a roff request: backslashes
+// and double quotes are escaped, and newlines are replaced by spaces.
+func roffQuote(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	text = strings.ReplaceAll(text, `"`, `\(dq`)
+	text = strings.ReplaceAll(text, "\n", " ")
+
+	return `"` + text + `"`
+}
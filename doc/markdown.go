@@ -0,0 +1,136 @@
+package doc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console"
+)
+
+// GenMarkdown writes the Markdown documentation of all the commands of a menu to w.
+// The document title is the given title, or the menu name if it is empty.
+func GenMarkdown(menu *console.Menu, title string, w io.Writer) error {
+	if title == "" {
+		title = menu.Name()
+	}
+
+	buf := new(bytes.Buffer)
+	cmds := commands(menu)
+
+	fmt.Fprintf(buf, "# %s\n\n", title)
+
+	if menu.Name() != "" {
+		fmt.Fprintf(buf, "Commands of the `%s` menu.\n\n", menu.Name())
+	}
+
+	// Summary
+	for _, cmd := range cmds {
+		fmt.Fprintf(buf, "* [%s](#%s)", path(cmd), anchor(path(cmd)))
+
+		if cmd.Short != "" {
+			fmt.Fprintf(buf, " - %s", cmd.Short)
+		}
+
+		buf.WriteString("\n")
+	}
+
+	for _, cmd := range cmds {
+		buf.WriteString("\n")
+		genMarkdownCommand(buf, cmd)
+	}
+
+	_, err := buf.WriteTo(w)
+
+	return err
+}
+
+// GenMarkdownTree writes the Markdown documentation of each console
+// menu to its own file in dir, named after BaseName with a .md extension.
+func GenMarkdownTree(c *console.Console, dir string) error {
+	for _, menu := range c.Menus() {
+		title := c.Name()
+		if menu.Name() != "" {
+			title += " " + menu.Name()
+		}
+
+		if err := genFile(filepath.Join(dir, BaseName(c, menu)+".md"), func(w io.Writer) error {
+			return GenMarkdown(menu, strings.TrimSpace(title), w)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func genMarkdownCommand(buf *bytes.Buffer, cmd *cobra.Command) {
+	fmt.Fprintf(buf, "## %s\n\n", path(cmd))
+
+	if cmd.Long != "" {
+		fmt.Fprintf(buf, "%s\n\n", cmd.Long)
+	} else if cmd.Short != "" {
+		fmt.Fprintf(buf, "%s\n\n", cmd.Short)
+	}
+
+	if cmd.Runnable() {
+		fmt.Fprintf(buf, "```\n%s\n```\n\n", usage(cmd))
+	}
+
+	if len(cmd.Aliases) > 0 {
+		fmt.Fprintf(buf, "**Aliases:** %s\n\n", strings.Join(cmd.Aliases, ", "))
+	}
+
+	if expr := filterExpression(cmd); expr != "" {
+		fmt.Fprintf(buf, "**Hidden when filters match:** `%s`\n\n", expr)
+	}
+
+	if flags := cmd.NonInheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(buf, "### Options\n\n```\n%s```\n\n", flags.FlagUsages())
+	}
+
+	if flags := cmd.InheritedFlags(); flags.HasAvailableFlags() {
+		fmt.Fprintf(buf, "### Options inherited from parent commands\n\n```\n%s```\n\n", flags.FlagUsages())
+	}
+
+	if flags := filteredFlags(cmd); len(flags) > 0 {
+		buf.WriteString("**Options hidden when filters match:**\n\n")
+
+		for _, flag := range flags {
+			fmt.Fprintf(buf, "* `--%s`: `%s`\n", flag.Name, flagFilterExpression(flag))
+		}
+
+		buf.WriteString("\n")
+	}
+
+	if cmd.Example != "" {
+		fmt.Fprintf(buf, "### Examples\n\n```\n%s\n```\n\n", cmd.Example)
+	}
+
+	buf.Truncate(buf.Len() - 1)
+}
+
+// anchor returns the GitHub-style Markdown anchor of a heading.
+func anchor(heading string) string {
+	return strings.ReplaceAll(strings.ToLower(heading), " ", "-")
+}
+
+// genFile creates the file at path, and writes it with gen.
+func genFile(path string, gen func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := gen(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
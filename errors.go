@@ -1,8 +1,11 @@
 package console
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"syscall"
 )
 
 type (
@@ -21,6 +24,7 @@ type (
 	Err struct {
 		err     error
 		message string
+		out     io.Writer // Where the default handler prints the error, if not os.Stderr.
 	}
 
 	// PreReadError is an error that occurs during the pre-read phase.
@@ -34,14 +38,78 @@ type (
 
 	// ExecutionError is an error that occurs during the execution phase.
 	ExecutionError struct{ Err }
+
+	// InterruptedError is returned when a command line run with RunScript (or
+	// at the prompt, with RunOnce) is interrupted by a signal, even though its
+	// command returned within the grace period, so that scripts stop there.
+	// Commands still running afterwards are abandoned (see AbandonedError).
+	InterruptedError struct {
+		Signal os.Signal // Signal which interrupted the command.
+	}
+
+	// ExitCoder is implemented by errors carrying an exit status.
+	//
+	// Commands can return such errors from their cobra RunE functions: the
+	// code is propagated to the ExecutionError, to the console last status
+	// (see Console.ExitStatus) and, when running non-interactively, should
+	// be used as the process exit status (see ExitCode).
+	ExitCoder interface {
+		error
+		ExitCode() int
+	}
 )
 
+// defaultErrorHandler prints errors to os.Stderr, or
+// to the client of the session in which they occurred.
 func defaultErrorHandler(err error) error {
-	fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+	var out io.Writer = os.Stderr
+
+	var consoleErr interface{ output() io.Writer }
+	if errors.As(err, &consoleErr) && consoleErr.output() != nil {
+		out = consoleErr.output()
+	}
+
+	fmt.Fprintf(out, "Error: %s\n", err)
 
 	return nil
 }
 
+// Error implements the error interface.
+func (e InterruptedError) Error() string {
+	return fmt.Sprintf("command interrupted by %s", e.Signal)
+}
+
+// ExitCode returns the exit status of a command killed by the signal.
+func (e InterruptedError) ExitCode() int {
+	if sig, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+
+	return 1
+}
+
+// ExitCode returns the exit status carried by an execution error.
+func (e ExecutionError) ExitCode() int {
+	return ExitCode(e.err)
+}
+
+// ExitCode returns the exit status corresponding to an error returned by the
+// console: 0 if err is nil, the code of the first ExitCoder found in its chain,
+// or 1 for any other error. This is typically used when running commands
+// non-interactively, like os.Exit(console.ExitCode(err)).
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var coder ExitCoder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+
+	return 1
+}
+
 // newError creates a new Err.
 func newError(err error, message string) Err {
 	return Err{
@@ -64,3 +132,8 @@ func (e Err) Error() string {
 func (e Err) Unwrap() error {
 	return e.err
 }
+
+// output returns where the default handler prints the error.
+func (e Err) output() io.Writer {
+	return e.out
+}
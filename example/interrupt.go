@@ -1,10 +1,9 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/reeflective/console"
 )
@@ -12,14 +11,7 @@ import (
 // exitCtrlD is a custom interrupt handler to use when the shell
 // readline receives an io.EOF error, which is returned with CtrlD.
 func exitCtrlD(c *console.Console) {
-	reader := bufio.NewReader(os.Stdin)
-
-	fmt.Print("Confirm exit (Y/y): ")
-
-	text, _ := reader.ReadString('\n')
-	answer := strings.TrimSpace(text)
-
-	if (answer == "Y") || (answer == "y") {
+	if yes, _ := c.Confirm(context.Background(), "Confirm exit?"); yes {
 		os.Exit(0)
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/reeflective/console"
+	"github.com/reeflective/console/commands"
 	"github.com/reeflective/console/commands/readline"
 )
 
@@ -33,15 +34,12 @@ func mainMenuCommands(app *console.Console) console.Commands {
 		// Readline subcommands
 		rootCmd.AddCommand(readline.Commands(app.Shell()))
 
-		exitCmd := &cobra.Command{
-			Use:     "exit",
-			Short:   "Exit the console application",
-			GroupID: "core",
-			Run: func(cmd *cobra.Command, args []string) {
-				exitCtrlD(app)
-			},
-		}
-		rootCmd.AddCommand(exitCmd)
+		// Help and search for commands in all menus.
+		rootCmd.SetHelpCommand(commands.Help(app))
+		rootCmd.AddCommand(commands.Apropos(app))
+
+		rootCmd.AddCommand(commands.Messages(app))
+		rootCmd.AddCommand(commands.Exit())
 
 		// And let's add a command declared in a traditional "cobra" way.
 		clientMenuCommand := &cobra.Command{
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/reeflective/console"
 )
@@ -78,6 +80,17 @@ func main() {
 
 	// Run the app -------------------------------------------------- //
 
+	// When arguments are given on the command-line, run them as a single
+	// command and exit with its status, like a traditional CLI would.
+	if len(os.Args) > 1 {
+		err := menu.RunCommandArgs(context.Background(), os.Args[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		}
+
+		os.Exit(console.ExitCode(err))
+	}
+
 	// Everything is ready for a tour.
 	// Run the console and take a look around.
 	app.Start()
@@ -0,0 +1,114 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/reeflective/console/internal/command"
+)
+
+// Result holds the outcome of a command executed with Menu.Exec.
+type Result struct {
+	Stdout   []byte        // Everything the command wrote to its cobra output.
+	Stderr   []byte        // Everything the command wrote to its cobra error output.
+	Err      error         // Error returned by the command execution, if any.
+	Status   int           // Exit status of the command (see Console.ExitStatus).
+	Duration time.Duration // Time taken by the command execution.
+}
+
+// Exec runs an unsplit command line in the menu, like Menu.RunCommandLine,
+// but captures the command output instead of printing it to the terminal.
+//
+// The target command's input and outputs are set (with cobra SetIn/SetOut/SetErr)
+// for the duration of the run, and restored afterwards: its input is empty and
+// its outputs are captured in the result. Therefore, only what commands print
+// through cmd.OutOrStdout() and cmd.ErrOrStderr() (or cmd.Print and the likes,
+// including cobra errors and usage) is captured: a command writing directly to
+// os.Stdout still prints to the terminal.
+//
+// The returned error is the execution error (also in Result.Err), or an error
+// splitting the line, in which case nothing is executed.
+func (m *Menu) Exec(ctx context.Context, input string) (Result, error) {
+	args, err := m.splitLine(input)
+	if err != nil || len(args) == 0 {
+		return Result{}, err
+	}
+
+	res := m.execArgs(ctx, args)
+
+	return res, res.Err
+}
+
+// execArgs executes already split arguments in the menu, capturing their outputs.
+func (m *Menu) execArgs(ctx context.Context, args []string) Result {
+	var res Result
+
+	// Like RunCommandArgs, work on a fresh command tree.
+	m.resetPreRun()
+
+	var stdout, stderr bytes.Buffer
+
+	target, _, _ := m.Command.Find(args)
+	if target == nil {
+		target = m.Command
+	}
+
+	restore := command.SetIO(target, bytes.NewReader(nil), &stdout, &stderr)
+	defer restore()
+
+	// Nobody can answer questions asked by the command.
+	ctx = context.WithValue(ctx, interactiveKey{}, false)
+
+	start := time.Now()
+	_, res.Err = m.console.execute(ctx, m, args, !m.console.isExecuting.Load())
+	res.Duration = time.Since(start)
+
+	res.Stdout = stdout.Bytes()
+	res.Stderr = stderr.Bytes()
+	res.Status = m.console.ExitStatus()
+
+	return res
+}
+
+// execDetached executes already split arguments on a new command tree of the
+// menu, capturing their outputs, like commands run in sessions are: neither
+// the menu command tree nor the console state (like its exit status) are
+// touched, so that it can be called while the console reads or runs commands.
+// The menu must have a command generator.
+func (m *Menu) execDetached(ctx context.Context, args []string) (Result, error) {
+	var res Result
+
+	if !m.hasGenerator() {
+		return res, fmt.Errorf("commands of menu %q can't run detached without a command generator (see Menu.SetCommands)", m.Name())
+	}
+
+	filters := m.activeFilters()
+	root := m.newCommands(filters)
+
+	var stdout, stderr bytes.Buffer
+
+	root.SetIn(bytes.NewReader(nil))
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+
+	// Nobody can answer questions asked by the command.
+	ctx = context.WithValue(ctx, interactiveKey{}, false)
+
+	start := time.Now()
+	_, res.Err = m.console.run(ctx, &execution{
+		menu:      m,
+		root:      root,
+		args:      args,
+		filters:   filters,
+		principal: m.console.Principal(),
+	})
+	res.Duration = time.Since(start)
+
+	res.Stdout = stdout.Bytes()
+	res.Stderr = stderr.Bytes()
+	res.Status = ExitCode(res.Err)
+
+	return res, nil
+}
@@ -0,0 +1,75 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestMenuExecCapturesOutput(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	var target *cobra.Command
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		target = &cobra.Command{
+			Use: "echo",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cmd.Println(strings.Join(args, " "))
+				cmd.PrintErrln("warning")
+
+				// Commands get an empty input when executed headless.
+				if in, _ := io.ReadAll(cmd.InOrStdin()); len(in) != 0 {
+					return errors.New("unexpected input")
+				}
+
+				return nil
+			},
+		}
+		root.AddCommand(target, &cobra.Command{
+			Use:           "fail",
+			SilenceErrors: true,
+			SilenceUsage:  true,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				cmd.Println("partial output")
+				return exitError{3}
+			},
+		})
+
+		return root
+	})
+
+	res, err := menu.Exec(context.Background(), `echo hello "big world"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(res.Stdout); got != "hello big world\n" {
+		t.Fatalf("stdout = %q, want %q", got, "hello big world\n")
+	}
+	if got := string(res.Stderr); got != "warning\n" {
+		t.Fatalf("stderr = %q, want %q", got, "warning\n")
+	}
+
+	// The command streams are restored once done.
+	if target.OutOrStdout() != os.Stdout || target.ErrOrStderr() != os.Stderr || target.InOrStdin() != os.Stdin {
+		t.Fatal("Exec did not restore the command input/outputs")
+	}
+
+	res, err = menu.Exec(context.Background(), "fail")
+	if err == nil || !errors.Is(res.Err, err) {
+		t.Fatalf("Exec(fail) error = %v, result error = %v", err, res.Err)
+	}
+	if res.Status != 3 {
+		t.Fatalf("Exec(fail) status = %d, want 3", res.Status)
+	}
+	if got := string(res.Stdout); got != "partial output\n" {
+		t.Fatalf("Exec(fail) stdout = %q, want %q", got, "partial output\n")
+	}
+}
@@ -1,7 +1,9 @@
 package console
 
 import (
+	"context"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -82,3 +84,146 @@ func TestCheckIsAvailable(t *testing.T) {
 		t.Fatalf("CheckIsAvailable(free) = %v, want nil (not filtered)", err)
 	}
 }
+
+func TestFilterExpressionsShowCommands(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	root := &cobra.Command{Use: "root"}
+	admin := &cobra.Command{Use: "admin", Annotations: map[string]string{CommandFilterKey: "!admin"}}
+	root.AddCommand(admin)
+
+	// Commands with a negated filter are hidden until the filter is active.
+	err := menu.CheckIsAvailable(admin)
+	if err == nil {
+		t.Fatal("CheckIsAvailable(admin) = nil, want error (admin filter not active)")
+	}
+
+	want := "Command admin is unavailable, hidden by filter expression \"!admin\":\n    - !admin"
+	if err.Error() != want {
+		t.Fatalf("error = %q, want %q", err, want)
+	}
+
+	c.HideCommands("admin")
+
+	if err := menu.CheckIsAvailable(admin); err != nil {
+		t.Fatalf("CheckIsAvailable(admin) with admin active = %v, want nil", err)
+	}
+}
+
+func TestMenuFilters(t *testing.T) {
+	c := New("test")
+	main := c.ActiveMenu()
+	session := c.NewMenu("session")
+
+	commands := func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{
+			Use:         "ps",
+			Annotations: map[string]string{CommandFilterKey: "windows"},
+			Run:         func(*cobra.Command, []string) {},
+		})
+
+		return root
+	}
+
+	main.SetCommands(commands)
+	session.SetCommands(commands)
+
+	session.HideCommands("windows")
+	c.HideCommands("admin")
+
+	// Menu filters are merged with the console ones, in their menu only.
+	if got := session.activeFilters(); !reflect.DeepEqual(got, []string{"admin", "windows"}) {
+		t.Fatalf("session filters = %v, want [admin windows]", got)
+	}
+	if got := main.activeFilters(); !reflect.DeepEqual(got, []string{"admin"}) {
+		t.Fatalf("main filters = %v, want [admin]", got)
+	}
+
+	session.resetPreRun()
+	main.resetPreRun()
+
+	ps, _, _ := session.Find([]string{"ps"})
+	if !ps.Hidden || !reflect.DeepEqual(session.ActiveFiltersFor(ps), []string{"windows"}) {
+		t.Fatal("ps should be hidden in the session menu")
+	}
+
+	ps, _, _ = main.Find([]string{"ps"})
+	if ps.Hidden || main.CheckIsAvailable(ps) != nil {
+		t.Fatal("ps should be available in the main menu")
+	}
+
+	// Console filters can't be removed from a menu.
+	session.ShowCommands("windows", "admin")
+
+	if got := session.activeFilters(); !reflect.DeepEqual(got, []string{"admin"}) {
+		t.Fatalf("session filters after ShowCommands = %v, want [admin]", got)
+	}
+}
+
+func TestFilteredFlags(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		group := &cobra.Command{Use: "proc"}
+		ps := &cobra.Command{Use: "ps", Run: func(*cobra.Command, []string) {}}
+		ps.Flags().Bool("registry", false, "")
+		_ = ps.Flags().SetAnnotation("registry", CommandFilterKey, []string{"!windows"})
+
+		group.AddCommand(ps)
+		root.AddCommand(group)
+
+		return root
+	})
+
+	if _, err := menu.Exec(context.Background(), "proc ps"); err != nil {
+		t.Fatalf("proc ps: %v", err)
+	}
+
+	_, err := menu.Exec(context.Background(), "proc ps --registry")
+	if err == nil || !strings.Contains(err.Error(), `flag --registry of command ps is unavailable, hidden by filter expression "!windows"`) {
+		t.Fatalf("proc ps --registry error = %v, want a filtered flag error", err)
+	}
+
+	res, _ := menu.Exec(context.Background(), "proc ps --help")
+	if strings.Contains(string(res.Stdout), "registry") {
+		t.Fatalf("help shows the filtered flag:\n%s", res.Stdout)
+	}
+
+	c.HideCommands("windows")
+
+	if _, err := menu.Exec(context.Background(), "proc ps --registry"); err != nil {
+		t.Fatalf("proc ps --registry with windows active: %v", err)
+	}
+}
+
+func TestFiltersWithoutGenerator(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	net, _, free := buildFilterTree()
+	menu.Command = net.Parent()
+
+	c.HideCommands("windows")
+	menu.resetPreRun()
+
+	if !net.Hidden || free.Hidden {
+		t.Fatal("filtered command not hidden in the menu tree")
+	}
+
+	// Copies of the tree are filtered on their own.
+	if copied, _, _ := menu.CommandTree().Find([]string{"net"}); copied.Hidden {
+		t.Fatal("filtered command hidden in the command tree copy")
+	}
+
+	// The same tree is filtered again with the new filters.
+	c.ShowCommands("windows")
+	menu.resetPreRun()
+
+	if net.Hidden {
+		t.Fatal("command still hidden once its filter is inactive")
+	}
+}
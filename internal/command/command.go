@@ -7,53 +7,83 @@ package command
 
 import (
 	"encoding/csv"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
-// FilterKey is the cobra annotation key whose comma-separated value marks a
-// command with the filters that hide it. The console re-exports this as
-// CommandFilterKey for application use.
+// FilterKey is the cobra annotation key whose value is a filter expression
+// (see Filter) hiding the command when it is true. The console re-exports
+// this as CommandFilterKey for application use.
 const FilterKey = "console-hidden"
 
-// ActiveFilters returns the console filters that cmd (or its nearest annotated
-// ancestor) declares itself incompatible with. A non-empty result means the
-// command is currently hidden/unavailable under the given console filters.
+// ActiveFilters returns the top-level terms of the filter expression of cmd (or of
+// its nearest ancestor hidden by its own) that are true with the console filters.
+// For a plain comma-separated list, these are the listed filters that are active.
+// A non-empty result means the command is currently hidden/unavailable.
 func ActiveFilters(cmd *cobra.Command, consoleFilters []string) []string {
-	if cmd.Annotations == nil {
-		if cmd.HasParent() {
-			return ActiveFilters(cmd.Parent(), consoleFilters)
-		}
+	_, terms := filteredBy(cmd, consoleFilters)
 
-		return nil
-	}
+	return terms
+}
 
-	// Get the filters declared on the command.
-	filterStr := cmd.Annotations[FilterKey]
-	var filters []string
+// FilterExpression returns the filter expression hiding cmd with the console
+// filters: its own, or the one of the ancestor hiding it, or "" if it is not hidden.
+func FilterExpression(cmd *cobra.Command, consoleFilters []string) string {
+	expr, _ := filteredBy(cmd, consoleFilters)
 
-	for _, cmdFilter := range strings.Split(filterStr, ",") {
-		for _, filter := range consoleFilters {
-			if cmdFilter != "" && cmdFilter == filter {
-				filters = append(filters, cmdFilter)
-			}
-		}
+	return expr
+}
+
+// filteredBy returns the filter expression hiding cmd and its active terms.
+// An invalid expression always hides its command, as a whole, since there
+// is no telling if the command was meant to be available or not.
+func filteredBy(cmd *cobra.Command, consoleFilters []string) (string, []string) {
+	expr := strings.TrimSpace(cmd.Annotations[FilterKey])
+
+	var terms []string
+
+	if filter, err := ParseFilter(expr); err != nil {
+		terms = []string{expr}
+	} else {
+		terms = filter.Active(consoleFilters)
 	}
 
-	if len(filters) > 0 || !cmd.HasParent() {
-		return filters
+	if len(terms) > 0 {
+		return expr, terms
 	}
 
 	// Any parent that is hidden makes its whole subtree hidden also.
-	return ActiveFilters(cmd.Parent(), consoleFilters)
+	if cmd.HasParent() {
+		return filteredBy(cmd.Parent(), consoleFilters)
+	}
+
+	return "", nil
+}
+
+// HideFiltered hides every command of the root tree (at any depth) that matches
+// an active console filter, so it is not shown in help strings or offered as a
+// completion, as well as all flags filtered the same way (see FlagActiveFilters).
+// Commands already hidden are left untouched, and so are their subcommands.
+// The commands and flags hidden are returned, so that they can be shown again.
+func HideFiltered(root *cobra.Command, consoleFilters []string) Hidden {
+	var hidden Hidden
+
+	hideFiltered(root, consoleFilters, &hidden)
+
+	return hidden
 }
 
-// HideFiltered hides every subcommand of root that matches an active console
-// filter, so it is not shown in help strings or offered as a completion.
-// Commands already hidden are left untouched.
-func HideFiltered(root *cobra.Command, consoleFilters []string) {
+func hideFiltered(root *cobra.Command, consoleFilters []string, hidden *Hidden) {
+	hideFilteredFlags(root.PersistentFlags(), consoleFilters, hidden)
+	hideFilteredFlags(root.Flags(), consoleFilters, hidden)
+
 	for _, cmd := range root.Commands() {
 		// Don't override commands if they are already hidden.
 		if cmd.Hidden {
@@ -62,8 +92,244 @@ func HideFiltered(root *cobra.Command, consoleFilters []string) {
 
 		if filters := ActiveFilters(cmd, consoleFilters); len(filters) > 0 {
 			cmd.Hidden = true
+			hidden.Commands = append(hidden.Commands, cmd)
+
+			continue
+		}
+
+		hideFiltered(cmd, consoleFilters, hidden)
+	}
+}
+
+// Hidden holds the commands and flags of a tree hidden by HideFiltered
+// or HideUnpermitted, which were visible before being hidden.
+type Hidden struct {
+	Commands []*cobra.Command
+	Flags    []*pflag.Flag
+}
+
+// Show shows the hidden commands and flags again, so that a tree
+// reused across filter changes can be hidden with the new filters.
+func (h Hidden) Show() {
+	for _, cmd := range h.Commands {
+		cmd.Hidden = false
+	}
+
+	for _, flag := range h.Flags {
+		flag.Hidden = false
+	}
+}
+
+// Clone returns a copy of the root tree, in which the commands and flags can be
+// hidden without affecting the tree: those hidden by any of shown are visible in
+// the copy. Flag values and annotations are shared with the tree, so the copy is
+// meant to be walked (to hide commands, generate documentation, etc), not executed.
+func Clone(root *cobra.Command, shown ...Hidden) *cobra.Command {
+	visible := make(map[any]bool)
+
+	for _, hidden := range shown {
+		for _, cmd := range hidden.Commands {
+			visible[cmd] = true
+		}
+
+		for _, flag := range hidden.Flags {
+			visible[flag] = true
+		}
+	}
+
+	return clone(root, visible)
+}
+
+func clone(cmd *cobra.Command, visible map[any]bool) *cobra.Command {
+	copied := *cmd
+	copied.ResetCommands()
+	copied.ResetFlags()
+
+	if visible[cmd] {
+		copied.Hidden = false
+	}
+
+	copyFlag := func(flags *pflag.FlagSet, flag *pflag.Flag) {
+		flagCopy := *flag
+		if visible[flag] {
+			flagCopy.Hidden = false
+		}
+
+		flags.AddFlag(&flagCopy)
+	}
+
+	// Once parsed, the flags of a command include its persistent
+	// ones and those inherited from its parents: only its own are
+	// copied, the others being inherited from the copied parents.
+	cmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		copyFlag(copied.PersistentFlags(), flag)
+	})
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if !inherited(cmd, flag) {
+			copyFlag(copied.Flags(), flag)
+		}
+	})
+
+	for _, sub := range cmd.Commands() {
+		copied.AddCommand(clone(sub, visible))
+	}
+
+	return &copied
+}
+
+// inherited returns true if the flag is a persistent
+// flag of the command, or of any of its parents.
+func inherited(cmd *cobra.Command, flag *pflag.Flag) bool {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if cmd.PersistentFlags().Lookup(flag.Name) == flag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FlagFilterExpression returns the filter expression of a flag, declared with
+// a FilterKey flag annotation, like with:
+//
+//	cmd.Flags().SetAnnotation("registry", FilterKey, []string{"!windows"})
+//
+// When the annotation has several values, they are or-ed together.
+func FlagFilterExpression(flag *pflag.Flag) string {
+	return strings.TrimSpace(strings.Join(flag.Annotations[FilterKey], ","))
+}
+
+// FlagActiveFilters returns the top-level terms of the filter expression of a
+// flag that are true with the console filters, like ActiveFilters does for
+// commands. Flags do not inherit the filters of their commands.
+func FlagActiveFilters(flag *pflag.Flag, consoleFilters []string) []string {
+	expr := FlagFilterExpression(flag)
+
+	filter, err := ParseFilter(expr)
+	if err != nil {
+		return []string{expr}
+	}
+
+	return filter.Active(consoleFilters)
+}
+
+// UsedFilteredFlags returns the flags of cmd hidden by the console filters that
+// are set when parsing args (the arguments following the command path), sorted
+// by name. Args are parsed like with ParsedFlags, leaving cmd untouched.
+func UsedFilteredFlags(cmd *cobra.Command, args []string, consoleFilters []string) []*pflag.Flag {
+	parsed := ParsedFlags(cmd, args)
+
+	names := make([]string, 0, len(parsed))
+	for name := range parsed {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var used []*pflag.Flag
+
+	for _, name := range names {
+		if flag := cmd.Flag(name); flag != nil && len(FlagActiveFilters(flag, consoleFilters)) > 0 {
+			used = append(used, flag)
+		}
+	}
+
+	return used
+}
+
+// ParsedFlags returns the values of the flags of cmd (local and inherited) set
+// when parsing args, mapped to their names. Args are parsed on a copy of the
+// command flags, so parsing errors are ignored, and the flags of cmd are left
+// untouched (their values and changed state included).
+func ParsedFlags(cmd *cobra.Command, args []string) map[string]string {
+	if cmd.DisableFlagParsing {
+		return nil
+	}
+
+	parsed := make(map[string]string)
+
+	parseCopy(cmd, args).Visit(func(flag *pflag.Flag) {
+		parsed[flag.Name] = flag.Value.String()
+	})
+
+	return parsed
+}
+
+// PositionalArgs returns the positional arguments in args (those that are
+// neither flags nor flag values), parsed like with ParsedFlags.
+func PositionalArgs(cmd *cobra.Command, args []string) []string {
+	if cmd.DisableFlagParsing {
+		return args
+	}
+
+	return parseCopy(cmd, args).Args()
+}
+
+// MissingRequiredFlags returns the visible flags of cmd marked as required
+// (with cobra MarkFlagRequired) that are not set when parsing args.
+func MissingRequiredFlags(cmd *cobra.Command, args []string) []*pflag.Flag {
+	parsed := ParsedFlags(cmd, args)
+
+	var missing []*pflag.Flag
+
+	visit := func(flag *pflag.Flag) {
+		if required := flag.Annotations[cobra.BashCompOneRequiredFlag]; len(required) == 0 || required[0] != "true" {
+			return
+		}
+
+		if _, set := parsed[flag.Name]; !set && !flag.Hidden && !slices.Contains(missing, flag) {
+			missing = append(missing, flag)
+		}
+	}
+
+	cmd.LocalFlags().VisitAll(visit)
+	cmd.InheritedFlags().VisitAll(visit)
+
+	return missing
+}
+
+// parseCopy parses args on a copy of the flags of cmd, accepting any value.
+func parseCopy(cmd *cobra.Command, args []string) *pflag.FlagSet {
+	parser := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	parser.ParseErrorsAllowlist.UnknownFlags = true
+	parser.SetOutput(io.Discard)
+
+	addFlag := func(flag *pflag.Flag) {
+		if parser.Lookup(flag.Name) != nil {
+			return
 		}
+
+		parser.AddFlag(&pflag.Flag{
+			Name:        flag.Name,
+			Shorthand:   flag.Shorthand,
+			NoOptDefVal: flag.NoOptDefVal,
+			Value:       &anyValue{},
+		})
 	}
+
+	cmd.LocalFlags().VisitAll(addFlag)
+	cmd.InheritedFlags().VisitAll(addFlag)
+
+	_ = parser.Parse(args)
+
+	return parser
+}
+
+// anyValue is a flag value accepting any string.
+type anyValue struct{ value string }
+
+func (v *anyValue) String() string     { return v.value }
+func (v *anyValue) Set(s string) error { v.value = s; return nil }
+func (v *anyValue) Type() string       { return "string" }
+
+func hideFilteredFlags(flags *pflag.FlagSet, consoleFilters []string, hidden *Hidden) {
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if !flag.Hidden && len(FlagActiveFilters(flag, consoleFilters)) > 0 {
+			flag.Hidden = true
+			hidden.Flags = append(hidden.Flags, flag)
+		}
+	})
 }
 
 // HideCarapace recursively hides carapace's internal _carapace completion
@@ -196,3 +462,51 @@ func findSubcommand(cmd *cobra.Command, name string) *cobra.Command {
 
 	return nil
 }
+
+// SetIO sets the input and outputs of cmd (through cobra's SetIn/SetOut/SetErr)
+// and returns a function restoring the previous ones. Since cobra does not tell
+// whether a command has its own streams or inherits them from its parents (or
+// from the os ones), streams equal to the inherited ones are restored as unset.
+func SetIO(cmd *cobra.Command, in io.Reader, out, errOut io.Writer) (restore func()) {
+	prevIn, prevOut, prevErr := cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr()
+
+	var parentIn io.Reader = os.Stdin
+	var parentOut, parentErr io.Writer = os.Stdout, os.Stderr
+
+	if cmd.HasParent() {
+		parentIn = cmd.Parent().InOrStdin()
+		parentOut, parentErr = cmd.Parent().OutOrStdout(), cmd.Parent().ErrOrStderr()
+	}
+
+	// Whether the streams are inherited must be known before setting them.
+	if inherits(prevIn, parentIn) {
+		prevIn = nil
+	}
+
+	if inherits(prevOut, parentOut) {
+		prevOut = nil
+	}
+
+	if inherits(prevErr, parentErr) {
+		prevErr = nil
+	}
+
+	cmd.SetIn(in)
+	cmd.SetOut(out)
+	cmd.SetErr(errOut)
+
+	return func() {
+		cmd.SetIn(prevIn)
+		cmd.SetOut(prevOut)
+		cmd.SetErr(prevErr)
+	}
+}
+
+// inherits reports whether the stream of a command is the one of its parent.
+// Streams of types which are not comparable (e.g. functions implementing
+// io.Writer) are never equal, and are thus considered set on the command.
+func inherits(stream, parent any) bool {
+	value := reflect.ValueOf(stream)
+
+	return value.IsValid() && value.Comparable() && value.Equal(reflect.ValueOf(parent))
+}
@@ -1,6 +1,8 @@
 package command
 
 import (
+	"bytes"
+	"os"
 	"reflect"
 	"testing"
 
@@ -164,3 +166,109 @@ func TestResetCompletionFlagState(t *testing.T) {
 func TestResetCompletionFlagStateNilSafe(t *testing.T) {
 	ResetCompletionFlagState(nil, nil) // must not panic
 }
+
+func TestSetIORestores(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	child := &cobra.Command{Use: "child"}
+	root.AddCommand(child)
+
+	// The root has its own output, the child inherits it.
+	var rootOut, captured bytes.Buffer
+	root.SetOut(&rootOut)
+
+	restore := SetIO(child, nil, &captured, &captured)
+	child.Print("captured")
+	restore()
+
+	if captured.String() != "captured" {
+		t.Fatalf("captured output = %q, want %q", captured.String(), "captured")
+	}
+
+	// Once restored, the child inherits the root output again.
+	root.SetOut(os.Stdout)
+	if child.OutOrStdout() != os.Stdout {
+		t.Fatal("child output was not restored as inherited")
+	}
+
+	// Streams set on the command itself are restored as is.
+	var own bytes.Buffer
+	child.SetOut(&own)
+
+	restore = SetIO(child, nil, &captured, &captured)
+	restore()
+
+	if child.OutOrStdout() != &own {
+		t.Fatal("child own output was not restored")
+	}
+}
+
+// writerFunc is a writer of a type which is not comparable.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestSetIOUncomparable(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	child := &cobra.Command{Use: "child"}
+	root.AddCommand(child)
+
+	var own bytes.Buffer
+	child.SetOut(writerFunc(own.Write))
+	root.SetOut(writerFunc(own.Write))
+
+	// Uncomparable streams are considered set on the command.
+	var captured bytes.Buffer
+	restore := SetIO(child, nil, &captured, &captured)
+	restore()
+
+	child.Print("own")
+
+	if own.String() != "own" {
+		t.Fatalf("own output = %q, want %q", own.String(), "own")
+	}
+}
+
+func TestCloneShowsHidden(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	root.PersistentFlags().Bool("verbose", false, "")
+
+	win := &cobra.Command{Use: "win", Annotations: map[string]string{FilterKey: "windows"}}
+	ls := &cobra.Command{Use: "ls"}
+	ls.Flags().String("registry", "", "")
+	_ = ls.Flags().SetAnnotation("registry", FilterKey, []string{"windows"})
+	root.AddCommand(win, ls)
+
+	// Parsing merges the persistent flags in the command ones.
+	_ = ls.ParseFlags(nil)
+
+	hidden := HideFiltered(root, []string{"windows"})
+
+	copied := Clone(root, hidden)
+	HideFiltered(copied, nil)
+
+	copiedWin, _, _ := copied.Find([]string{"win"})
+	copiedLs, _, _ := copied.Find([]string{"ls"})
+
+	if copiedWin == win || copiedWin.Hidden {
+		t.Fatal("filtered command is not a visible copy")
+	}
+
+	if flag := copiedLs.Flags().Lookup("registry"); flag == nil || flag.Hidden {
+		t.Fatal("filtered flag is not a visible copy")
+	}
+
+	if copiedLs.Flag("verbose") == nil || copiedLs.LocalFlags().Lookup("verbose") != nil {
+		t.Fatal("persistent flag is not inherited by the copy")
+	}
+
+	// The tree itself is untouched, until shown again.
+	if !win.Hidden || !ls.Flags().Lookup("registry").Hidden {
+		t.Fatal("tree changed by its copy")
+	}
+
+	hidden.Show()
+
+	if win.Hidden || ls.Flags().Lookup("registry").Hidden {
+		t.Fatal("hidden command and flag are not shown again")
+	}
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ConfirmKey is the cobra annotation key whose value is the question asked
+// to the user before running the command. The console re-exports this as
+// CommandConfirmKey for application use.
+const ConfirmKey = "console-confirm"
+
+// YesFlag is the name of the flag added to commands requiring a
+// confirmation, with which the confirmation is given in advance.
+const YesFlag = "yes"
+
+// Confirmation returns the question to ask before running cmd, if any.
+func Confirmation(cmd *cobra.Command) string {
+	return strings.TrimSpace(cmd.Annotations[ConfirmKey])
+}
+
+// AddConfirmFlags adds the YesFlag to every command of the root tree (at any
+// depth) requiring a confirmation, unless it already has a flag with this name.
+func AddConfirmFlags(root *cobra.Command) {
+	for _, cmd := range root.Commands() {
+		if Confirmation(cmd) != "" && cmd.Flag(YesFlag) == nil {
+			cmd.Flags().Bool(YesFlag, false, "Run the command without asking for confirmation")
+		}
+
+		AddConfirmFlags(cmd)
+	}
+}
+
+// Confirmed returns true if the YesFlag is set to true when parsing args
+// (the arguments following the command path), leaving cmd untouched.
+func Confirmed(cmd *cobra.Command, args []string) bool {
+	value, set := ParsedFlags(cmd, args)[YesFlag]
+	if !set {
+		return false
+	}
+
+	yes, err := strconv.ParseBool(value)
+
+	return err == nil && yes
+}
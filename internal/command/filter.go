@@ -0,0 +1,265 @@
+package command
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Filter is a parsed filter expression, as found in FilterKey annotations.
+//
+// Expressions combine filter names with `!` (not), `&&` or `&` (and), `||`,
+// `|` or `,` (or), and parentheses, with the usual precedence (not, and, or).
+// A command is hidden when its expression is true, a filter name being true
+// when the filter is active. A plain comma-separated list thus hides the
+// command as soon as one of its filters is active.
+//
+// Examples:
+//
+//	windows,linux       hidden when windows or linux are active
+//	!admin              hidden unless admin is active
+//	windows && !beacon  hidden when windows is active, unless beacon is too
+type Filter struct {
+	terms []filterNode // Top-level terms of the expression, or-ed together.
+}
+
+// filterNode is a node of a filter expression syntax tree.
+type filterNode struct {
+	op       byte // 0 for a filter name, or one of '!', '&', '|'.
+	name     string
+	operands []filterNode
+	text     string // Source text of the node.
+}
+
+// ParseFilter parses a filter expression. An empty expression never hides anything.
+func ParseFilter(expr string) (Filter, error) {
+	parser := &filterParser{input: expr}
+
+	parser.skipSpaces()
+
+	if parser.done() {
+		return Filter{}, nil
+	}
+
+	terms, err := parser.parseTerms()
+	if err != nil {
+		return Filter{}, err
+	}
+
+	if !parser.done() {
+		return Filter{}, parser.errorf("unexpected %q", parser.input[parser.pos:])
+	}
+
+	return Filter{terms: terms}, nil
+}
+
+// Eval returns true if the expression is true with the given active filters.
+func (f Filter) Eval(active []string) bool {
+	return len(f.Active(active)) > 0
+}
+
+// Active returns the source text of the top-level terms of the expression
+// (the operands of its outermost or) that are true with the given filters.
+func (f Filter) Active(active []string) []string {
+	var terms []string
+
+	for _, term := range f.terms {
+		if term.eval(active) {
+			terms = append(terms, term.text)
+		}
+	}
+
+	return terms
+}
+
+// Names returns all the filter names used in the expression.
+func (f Filter) Names() []string {
+	var names []string
+
+	var walk func(node filterNode)
+	walk = func(node filterNode) {
+		if node.op == 0 && !slices.Contains(names, node.name) {
+			names = append(names, node.name)
+		}
+
+		for _, operand := range node.operands {
+			walk(operand)
+		}
+	}
+
+	for _, term := range f.terms {
+		walk(term)
+	}
+
+	return names
+}
+
+func (n filterNode) eval(active []string) bool {
+	switch n.op {
+	case '!':
+		return !n.operands[0].eval(active)
+	case '&':
+		for _, operand := range n.operands {
+			if !operand.eval(active) {
+				return false
+			}
+		}
+
+		return true
+	case '|':
+		for _, operand := range n.operands {
+			if operand.eval(active) {
+				return true
+			}
+		}
+
+		return false
+	default:
+		return slices.Contains(active, n.name)
+	}
+}
+
+// filterParser is a recursive descent parser for filter expressions.
+type filterParser struct {
+	input string
+	pos   int
+}
+
+// parseTerms parses the operands of an or expression.
+func (p *filterParser) parseTerms() ([]filterNode, error) {
+	var terms []filterNode
+
+	for {
+		term, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		terms = append(terms, term)
+
+		if !p.accept("||") && !p.accept("|") && !p.accept(",") {
+			return terms, nil
+		}
+	}
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	start := p.pos
+
+	terms, err := p.parseTerms()
+	if err != nil || len(terms) == 1 {
+		return first(terms), err
+	}
+
+	return filterNode{op: '|', operands: terms, text: p.text(start)}, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	start := p.pos
+
+	var operands []filterNode
+
+	for {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return filterNode{}, err
+		}
+
+		operands = append(operands, operand)
+
+		if !p.accept("&&") && !p.accept("&") {
+			break
+		}
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+
+	return filterNode{op: '&', operands: operands, text: p.text(start)}, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	start := p.pos
+
+	switch {
+	case p.accept("!"):
+		operand, err := p.parseUnary()
+		if err != nil {
+			return filterNode{}, err
+		}
+
+		return filterNode{op: '!', operands: []filterNode{operand}, text: p.text(start)}, nil
+
+	case p.accept("("):
+		node, err := p.parseOr()
+		if err != nil {
+			return filterNode{}, err
+		}
+
+		if !p.accept(")") {
+			return filterNode{}, p.errorf("missing closing parenthesis")
+		}
+
+		node.text = p.text(start)
+
+		return node, nil
+	}
+
+	name := p.pos
+	for !p.done() && !strings.ContainsRune("!&|,() \t", rune(p.input[p.pos])) {
+		p.pos++
+	}
+
+	if name == p.pos {
+		if p.done() {
+			return filterNode{}, p.errorf("unexpected end of expression")
+		}
+
+		return filterNode{}, p.errorf("unexpected %q", p.input[p.pos])
+	}
+
+	node := filterNode{name: p.input[name:p.pos], text: p.input[name:p.pos]}
+	p.skipSpaces()
+
+	return node, nil
+}
+
+// accept consumes token (and the following spaces) if it is next in the input.
+func (p *filterParser) accept(token string) bool {
+	if !strings.HasPrefix(p.input[p.pos:], token) {
+		return false
+	}
+
+	p.pos += len(token)
+	p.skipSpaces()
+
+	return true
+}
+
+func (p *filterParser) skipSpaces() {
+	for !p.done() && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+// text returns the source text from start to the current position.
+func (p *filterParser) text(start int) string {
+	return strings.TrimSpace(p.input[start:p.pos])
+}
+
+func (p *filterParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("invalid filter expression %q at offset %d: %s", p.input, p.pos, fmt.Sprintf(format, args...))
+}
+
+func first(nodes []filterNode) filterNode {
+	if len(nodes) == 0 {
+		return filterNode{}
+	}
+
+	return nodes[0]
+}
@@ -0,0 +1,160 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestFilterExpressions(t *testing.T) {
+	tests := []struct {
+		expr   string
+		active []string
+		want   []string
+	}{
+		{"", []string{"windows"}, nil},
+		{"windows", []string{"windows"}, []string{"windows"}},
+		{"windows,linux", []string{"linux"}, []string{"linux"}},
+		{"windows, linux", []string{"windows", "linux"}, []string{"windows", "linux"}},
+		{"!admin", nil, []string{"!admin"}},
+		{"!admin", []string{"admin"}, nil},
+		{"windows&&!beacon", []string{"windows"}, []string{"windows&&!beacon"}},
+		{"windows&&!beacon", []string{"windows", "beacon"}, nil},
+		{"windows & !beacon", []string{"windows"}, []string{"windows & !beacon"}},
+		{"session|offline", []string{"offline"}, []string{"offline"}},
+		{"session || offline", []string{"session"}, []string{"session"}},
+		{"a && (b | c)", []string{"a", "c"}, []string{"a && (b | c)"}},
+		{"a && (b | c)", []string{"b", "c"}, nil},
+		{"!(a | b), c", []string{"c"}, []string{"!(a | b)", "c"}},
+		{"a | b && c", []string{"b"}, nil},
+		{"!!a", []string{"a"}, []string{"!!a"}},
+	}
+
+	for _, tc := range tests {
+		filter, err := ParseFilter(tc.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tc.expr, err)
+			continue
+		}
+
+		if got := filter.Active(tc.active); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q with %v active = %q, want %q", tc.expr, tc.active, got, tc.want)
+		}
+
+		if got := filter.Eval(tc.active); got != (len(tc.want) > 0) {
+			t.Errorf("%q with %v active evaluates to %v", tc.expr, tc.active, got)
+		}
+	}
+}
+
+func TestFilterNames(t *testing.T) {
+	filter, err := ParseFilter("windows && !(beacon | admin), windows")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := filter.Names(); !reflect.DeepEqual(got, []string{"windows", "beacon", "admin"}) {
+		t.Fatalf("Names() = %v, want [windows beacon admin]", got)
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, expr := range []string{"!", "a &&", "(a | b", "a b", "a,,b", "a)", "&a"} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q) = nil error, want one", expr)
+		}
+	}
+}
+
+// Commands with invalid expressions are always hidden.
+func TestActiveFiltersInvalidExpression(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	cmd := filtered("cmd", "windows &&")
+	root.AddCommand(cmd)
+
+	if got := ActiveFilters(cmd, nil); !reflect.DeepEqual(got, []string{"windows &&"}) {
+		t.Fatalf("ActiveFilters with an invalid expression = %v, want the expression", got)
+	}
+
+	if got := FilterExpression(cmd, nil); got != "windows &&" {
+		t.Fatalf("FilterExpression = %q, want the invalid expression", got)
+	}
+}
+
+func TestHideFilteredNestedAndFlags(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	group := &cobra.Command{Use: "group"}
+	nested := filtered("nested", "windows")
+	deep := &cobra.Command{Use: "deep"}
+	plain := &cobra.Command{Use: "plain"}
+
+	root.AddCommand(group)
+	group.AddCommand(nested, plain)
+	nested.AddCommand(deep)
+
+	plain.Flags().Bool("registry", false, "")
+	plain.Flags().Bool("verbose", false, "")
+	_ = plain.Flags().SetAnnotation("registry", FilterKey, []string{"!windows"})
+	root.PersistentFlags().String("proxy", "", "")
+	_ = root.PersistentFlags().SetAnnotation("proxy", FilterKey, []string{"offline"})
+
+	HideFiltered(root, []string{"offline"})
+
+	if nested.Hidden || group.Hidden || plain.Hidden {
+		t.Fatalf("hidden: nested %v, group %v, plain %v, want none", nested.Hidden, group.Hidden, plain.Hidden)
+	}
+	if !plain.Flags().Lookup("registry").Hidden || plain.Flags().Lookup("verbose").Hidden {
+		t.Fatal("only the registry flag should be hidden without windows")
+	}
+	if !root.PersistentFlags().Lookup("proxy").Hidden {
+		t.Fatal("the persistent proxy flag should be hidden when offline")
+	}
+
+	HideFiltered(root, []string{"windows"})
+
+	if !nested.Hidden {
+		t.Fatal("nested windows command should be hidden")
+	}
+	if got := ActiveFilters(deep, []string{"windows"}); !reflect.DeepEqual(got, []string{"windows"}) {
+		t.Fatalf("deep command filters = %v, want [windows] (inherited)", got)
+	}
+}
+
+func TestUsedFilteredFlags(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	cmd := &cobra.Command{Use: "cmd"}
+	root.AddCommand(cmd)
+
+	cmd.Flags().BoolP("registry", "r", false, "")
+	cmd.Flags().StringSlice("item", []string{"base"}, "")
+	cmd.Flags().String("host", "", "")
+	_ = cmd.Flags().SetAnnotation("registry", FilterKey, []string{"windows"})
+	_ = cmd.Flags().SetAnnotation("item", FilterKey, []string{"windows"})
+	root.PersistentFlags().String("proxy", "", "")
+	_ = root.PersistentFlags().SetAnnotation("proxy", FilterKey, []string{"offline"})
+
+	names := func(args []string, filters ...string) []string {
+		var names []string
+		for _, flag := range UsedFilteredFlags(cmd, args, filters) {
+			names = append(names, flag.Name)
+		}
+
+		return names
+	}
+
+	if got := names([]string{"--host", "h", "--unknown"}, "windows"); got != nil {
+		t.Fatalf("used filtered flags = %v, want none", got)
+	}
+	if got := names([]string{"-r", "--item=a", "arg"}, "windows"); !reflect.DeepEqual(got, []string{"item", "registry"}) {
+		t.Fatalf("used filtered flags = %v, want [item registry]", got)
+	}
+	if got := names([]string{"--proxy", "p", "--", "--registry"}, "offline", "windows"); !reflect.DeepEqual(got, []string{"proxy"}) {
+		t.Fatalf("used filtered flags = %v, want [proxy]", got)
+	}
+
+	// The command flags are not touched.
+	if item := cmd.Flags().Lookup("item"); item.Changed || item.Value.String() != "[base]" {
+		t.Fatalf("item flag was modified: %v", item.Value)
+	}
+}
@@ -0,0 +1,80 @@
+package command
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// RolesKey is the cobra annotation key whose comma-separated value lists
+// the roles allowed to run a command (any of them is enough). The console
+// re-exports this as CommandRolesKey for application use.
+const RolesKey = "console-roles"
+
+// Roles returns the roles declared on cmd itself with RolesKey.
+func Roles(cmd *cobra.Command) []string {
+	var roles []string
+
+	for _, role := range strings.Split(cmd.Annotations[RolesKey], ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles
+}
+
+// MissingRoles returns the roles required to run cmd that are not satisfied by
+// the given ones, or nil if cmd can be run. Every command in the path of cmd
+// declaring roles (itself and its ancestors) must be satisfied, by holding at
+// least one of them: the result is the roles of the first one, from the root,
+// that is not satisfied.
+func MissingRoles(cmd *cobra.Command, roles []string) []string {
+	if cmd.HasParent() {
+		if missing := MissingRoles(cmd.Parent(), roles); len(missing) > 0 {
+			return missing
+		}
+	}
+
+	required := Roles(cmd)
+	if len(required) == 0 {
+		return nil
+	}
+
+	for _, role := range required {
+		if slices.Contains(roles, role) {
+			return nil
+		}
+	}
+
+	return required
+}
+
+// HideUnpermitted hides every command of the root tree (at any depth) that
+// cannot be run with the given roles. Commands already hidden are left untouched.
+// The commands hidden are returned, so that they can be shown again.
+func HideUnpermitted(root *cobra.Command, roles []string) Hidden {
+	var hidden Hidden
+
+	hideUnpermitted(root, roles, &hidden)
+
+	return hidden
+}
+
+func hideUnpermitted(root *cobra.Command, roles []string, hidden *Hidden) {
+	for _, cmd := range root.Commands() {
+		if cmd.Hidden {
+			continue
+		}
+
+		if len(MissingRoles(cmd, roles)) > 0 {
+			cmd.Hidden = true
+			hidden.Commands = append(hidden.Commands, cmd)
+
+			continue
+		}
+
+		hideUnpermitted(cmd, roles, hidden)
+	}
+}
@@ -0,0 +1,48 @@
+package command
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func withRoles(use, roles string) *cobra.Command {
+	return &cobra.Command{Use: use, Annotations: map[string]string{RolesKey: roles}}
+}
+
+func TestMissingRoles(t *testing.T) {
+	root := &cobra.Command{Use: "root"}
+	admin := withRoles("admin", "admin, operator")
+	wipe := withRoles("wipe", "admin")
+	status := &cobra.Command{Use: "status"}
+
+	root.AddCommand(admin)
+	admin.AddCommand(wipe, status)
+
+	tests := []struct {
+		cmd   *cobra.Command
+		roles []string
+		want  []string
+	}{
+		{root, nil, nil},
+		{admin, nil, []string{"admin", "operator"}},
+		{admin, []string{"operator"}, nil},
+		{status, []string{"operator"}, nil},
+		{status, []string{"guest"}, []string{"admin", "operator"}},
+		{wipe, []string{"operator"}, []string{"admin"}},
+		{wipe, []string{"admin"}, nil},
+	}
+
+	for _, tc := range tests {
+		if got := MissingRoles(tc.cmd, tc.roles); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("MissingRoles(%s, %v) = %v, want %v", tc.cmd.Name(), tc.roles, got, tc.want)
+		}
+	}
+
+	HideUnpermitted(root, []string{"operator"})
+
+	if admin.Hidden || status.Hidden || !wipe.Hidden {
+		t.Fatalf("hidden: admin %v, status %v, wipe %v, want only wipe", admin.Hidden, status.Hidden, wipe.Hidden)
+	}
+}
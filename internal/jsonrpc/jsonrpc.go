@@ -0,0 +1,181 @@
+// Package jsonrpc implements a minimal JSON-RPC 2.0 server, exchanging
+// newline-delimited messages over a stream (a socket, or stdin/stdout).
+// It is used by the console control endpoint and its MCP tool server.
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Version is the JSON-RPC protocol version.
+const Version = "2.0"
+
+// Standard JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Request is a JSON-RPC request, or a notification if it has no ID.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response. It has either a result, which is
+// "null" for handlers returning nil without error, or an error.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object. Handlers can return
+// one to control the code sent back to the client.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// Errorf returns an error with the given code and formatted message.
+func Errorf(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Handler handles the parameters of a request, returning its result.
+type Handler func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Server dispatches requests to the handlers registered for their methods.
+type Server struct {
+	handlers map[string]Handler
+}
+
+// NewServer returns a server without any method.
+func NewServer() *Server {
+	return &Server{handlers: make(map[string]Handler)}
+}
+
+// Handle registers the handler of a method.
+func (s *Server) Handle(method string, handler Handler) {
+	s.handlers[method] = handler
+}
+
+// Serve reads requests from r and writes their responses to w, until r is
+// exhausted (returning nil), fails, or the context is canceled. Requests are
+// handled one after the other, in the order they are received.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var mutex sync.Mutex
+
+	encoder := json.NewEncoder(w)
+	send := func(resp *Response) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return encoder.Encode(resp)
+	}
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		resp := s.handle(ctx, scanner.Bytes())
+		if resp == nil {
+			continue
+		}
+
+		if err := send(resp); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// handle processes a single message, returning
+// its response, or nil if it is a notification.
+func (s *Server) handle(ctx context.Context, msg []byte) *Response {
+	var req Request
+
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return &Response{
+			JSONRPC: Version,
+			ID:      json.RawMessage("null"),
+			Error:   Errorf(CodeParseError, "parse error: %s", err),
+		}
+	}
+
+	resp := &Response{JSONRPC: Version, ID: req.ID}
+	notification := len(req.ID) == 0
+
+	if req.JSONRPC != Version || req.Method == "" {
+		resp.Error = Errorf(CodeInvalidRequest, "invalid request")
+	} else if handler, found := s.handlers[req.Method]; !found {
+		resp.Error = Errorf(CodeMethodNotFound, "method not found: %s", req.Method)
+	} else {
+		resp.Result, resp.Error = call(ctx, handler, req.Params)
+	}
+
+	if notification {
+		return nil
+	}
+
+	return resp
+}
+
+func call(ctx context.Context, handler Handler, params json.RawMessage) (json.RawMessage, *Error) {
+	result, err := handler(ctx, params)
+	if err == nil {
+		var data []byte
+
+		if data, err = json.Marshal(result); err == nil {
+			return data, nil
+		}
+	}
+
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return nil, rpcErr
+	}
+
+	return nil, &Error{Code: CodeInternalError, Message: err.Error()}
+}
+
+// Decode unmarshals request parameters into v, returning
+// an invalid params error if they cannot be decoded.
+func Decode(params json.RawMessage, v any) error {
+	if len(params) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(params, v); err != nil {
+		return Errorf(CodeInvalidParams, "invalid params: %s", err)
+	}
+
+	return nil
+}
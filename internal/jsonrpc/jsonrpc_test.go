@@ -0,0 +1,73 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	server := NewServer()
+	server.Handle("sum", func(_ context.Context, params json.RawMessage) (any, error) {
+		var args []int
+		if err := Decode(params, &args); err != nil {
+			return nil, err
+		}
+
+		sum := 0
+		for _, arg := range args {
+			sum += arg
+		}
+
+		return sum, nil
+	})
+	server.Handle("nothing", func(context.Context, json.RawMessage) (any, error) {
+		return nil, nil
+	})
+	server.Handle("fail", func(context.Context, json.RawMessage) (any, error) {
+		return nil, errors.New("failed")
+	})
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"sum","params":[1,2,3]}`,
+		`{"jsonrpc":"2.0","method":"sum","params":[1]}`,
+		``,
+		`{"jsonrpc":"2.0","id":"a","method":"sum","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"fail"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"nothing"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"none"}`,
+		`{"id":4,"method":"sum"}`,
+		`not json`,
+	}, "\n")
+
+	var out strings.Builder
+	if err := server.Serve(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`{"jsonrpc":"2.0","id":1,"result":6}`,
+		`{"jsonrpc":"2.0","id":"a","error":{"code":-32602,"message":"invalid params: json: cannot unmarshal object into Go value of type []int"}}`,
+		`{"jsonrpc":"2.0","id":2,"error":{"code":-32603,"message":"failed"}}`,
+		`{"jsonrpc":"2.0","id":5,"result":null}`,
+		`{"jsonrpc":"2.0","id":3,"error":{"code":-32601,"message":"method not found: none"}}`,
+		`{"jsonrpc":"2.0","id":4,"error":{"code":-32600,"message":"invalid request"}}`,
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(want)+1 {
+		t.Fatalf("got %d responses, want %d:\n%s", len(lines), len(want)+1, out.String())
+	}
+
+	for i, line := range want {
+		if lines[i] != line {
+			t.Errorf("response %d = %s, want %s", i, lines[i], line)
+		}
+	}
+
+	if last := lines[len(lines)-1]; !strings.Contains(last, `"id":null`) || !strings.Contains(last, "-32700") {
+		t.Errorf("parse error response = %s", last)
+	}
+}
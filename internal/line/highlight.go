@@ -24,8 +24,10 @@ var (
 	ReverseReset    = "\x1b[27m"
 
     // Colors
+	RedFG    = "\x1b[31m"
 	GreenFG  = "\x1b[32m"
 	YellowFG = "\x1b[33m"
+	CyanFG   = "\x1b[36m"
 	ResetFG  = "\x1b[39m"
 	BrightWhiteFG = "\x1b[38;05;244m"
 )
@@ -0,0 +1,32 @@
+package strutil
+
+// Distance returns the Levenshtein distance between a and b, that is,
+// the number of rune insertions, deletions or substitutions needed to
+// turn one into the other. The comparison is case-sensitive.
+func Distance(a, b string) int {
+	source, target := []rune(a), []rune(b)
+
+	// Only keep the previous row of the distance matrix.
+	row := make([]int, len(target)+1)
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(source); i++ {
+		prev := row[0]
+		row[0] = i
+
+		for j := 1; j <= len(target); j++ {
+			cost := 1
+			if source[i-1] == target[j-1] {
+				cost = 0
+			}
+
+			current := row[j]
+			row[j] = min(row[j]+1, row[j-1]+1, prev+cost)
+			prev = current
+		}
+	}
+
+	return row[len(target)]
+}
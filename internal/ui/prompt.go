@@ -1,9 +1,7 @@
 package ui
 
 import (
-	"bytes"
 	"fmt"
-	"strings"
 
 	"github.com/reeflective/readline"
 )
@@ -20,8 +18,9 @@ type Prompt struct {
 }
 
 // NewPrompt requires the name of the application and the current menu,
-// as well as the current menu output buffer to produce a new, default prompt.
-func NewPrompt(appName, menuName string, stdout *bytes.Buffer) *Prompt {
+// as well as a function returning the number of unread messages queued
+// in the other menus, to produce a new, default prompt.
+func NewPrompt(appName, menuName string, unread func() int) *Prompt {
 	prompt := &Prompt{}
 
 	prompt.Primary = func() string {
@@ -33,10 +32,10 @@ func NewPrompt(appName, menuName string, stdout *bytes.Buffer) *Prompt {
 
 		promptStr += fmt.Sprintf(" [%s]", menuName)
 
-		// If the buffered command output is not empty,
-		// add a special status indicator to the prompt.
-		if strings.TrimSpace(stdout.String()) != "" {
-			promptStr += " $(...)"
+		// If messages were printed to other menus while they were
+		// not active, add a special status indicator to the prompt.
+		if count := unread(); count > 0 {
+			promptStr += fmt.Sprintf(" $(%d)", count)
 		}
 
 		return promptStr + " > "
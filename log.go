@@ -0,0 +1,168 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/reeflective/console/internal/line"
+)
+
+// LogHandler is a log/slog handler printing records with the console: above the
+// prompt (with TransientPrintf) while the console is reading input, and on plain
+// stdout while a command is running, so that logs of background goroutines never
+// corrupt the input line. Records are printed on one line, with their level
+// (colored unless the NO_COLOR environment variable is set), message and attributes.
+//
+// Records are printed if their level is at least the level of the active menu
+// (see Menu.SetLogLevel), or the handler level if the menu has none.
+type LogHandler struct {
+	console *Console
+	level   slog.Leveler
+	color   bool
+
+	text  slog.Handler  // Formats the record attributes.
+	buf   *bytes.Buffer // Output of the text handler.
+	mutex *sync.Mutex   // Shared by all handlers derived from the same one.
+}
+
+// NewLogHandler returns a log handler printing records with the console. Its
+// options are used like those of a slog.TextHandler, and the level defaults
+// to slog.LevelInfo. Example:
+//
+//	logger := slog.New(app.NewLogHandler(nil))
+//	logger.Info("listener started", "port", 8080)
+func (c *Console) NewLogHandler(opts *slog.HandlerOptions) *LogHandler {
+	var textOpts slog.HandlerOptions
+
+	if opts != nil {
+		textOpts = *opts
+	}
+
+	handler := &LogHandler{
+		console: c,
+		level:   textOpts.Level,
+		color:   os.Getenv("NO_COLOR") == "",
+		buf:     new(bytes.Buffer),
+		mutex:   new(sync.Mutex),
+	}
+
+	if handler.level == nil {
+		handler.level = slog.LevelInfo
+	}
+
+	// The time, level and message are printed by the handler itself.
+	replace := textOpts.ReplaceAttr
+	textOpts.Level = slog.LevelDebug - 8
+	textOpts.ReplaceAttr = func(groups []string, attr slog.Attr) slog.Attr {
+		if len(groups) == 0 {
+			switch attr.Key {
+			case slog.TimeKey, slog.LevelKey, slog.MessageKey:
+				return slog.Attr{}
+			}
+		}
+
+		if replace != nil {
+			return replace(groups, attr)
+		}
+
+		return attr
+	}
+
+	handler.text = slog.NewTextHandler(handler.buf, &textOpts)
+
+	return handler
+}
+
+// Enabled implements slog.Handler.
+func (h *LogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	threshold := h.level
+
+	if menuLevel := h.console.activeMenu().logLevelOrNil(); menuLevel != nil {
+		threshold = menuLevel
+	}
+
+	return level >= threshold.Level()
+}
+
+// Handle implements slog.Handler.
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.buf.Reset()
+
+	if err := h.text.Handle(ctx, record); err != nil {
+		return err
+	}
+
+	msg := h.levelString(record.Level) + " " + record.Message
+	if attrs := strings.TrimSpace(h.buf.String()); attrs != "" {
+		msg += " " + attrs
+	}
+
+	h.console.printLine(msg)
+
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *h
+	handler.text = h.text.WithAttrs(attrs)
+
+	return &handler
+}
+
+// WithGroup implements slog.Handler.
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	handler := *h
+	handler.text = h.text.WithGroup(name)
+
+	return &handler
+}
+
+// levelString returns the level of a record, padded and colored.
+func (h *LogHandler) levelString(level slog.Level) string {
+	label := fmt.Sprintf("%-5s", level.String())
+
+	if !h.color {
+		return label
+	}
+
+	var color string
+
+	switch {
+	case level >= slog.LevelError:
+		color = line.RedFG
+	case level >= slog.LevelWarn:
+		color = line.YellowFG
+	case level >= slog.LevelInfo:
+		color = line.CyanFG
+	default:
+		color = line.BrightWhiteFG
+	}
+
+	return color + label + line.ResetFG
+}
+
+// SetLogLevel sets the minimum level of the records printed by console log
+// handlers (see Console.NewLogHandler) while this menu is the active one,
+// overriding the level of the handlers. A nil level removes the override.
+func (m *Menu) SetLogLevel(level slog.Leveler) {
+	m.mutex.Lock()
+	m.logLevel = level
+	m.mutex.Unlock()
+}
+
+// logLevelOrNil returns the level set with SetLogLevel, if any.
+func (m *Menu) logLevelOrNil() slog.Leveler {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.logLevel
+}
@@ -0,0 +1,40 @@
+package console
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogHandler(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	c := New("test")
+	c.isExecuting.Store(true)
+
+	logger := slog.New(c.NewLogHandler(nil)).With("component", "listener")
+
+	out := captureStdout(t, func() {
+		logger.Debug("hidden")
+		logger.WithGroup("conn").Info("started", "port", 8080)
+		logger.Error("failed", "err", "100% broken")
+	})
+
+	want := "INFO  started component=listener conn.port=8080\n" +
+		"ERROR failed component=listener err=\"100% broken\"\n"
+	if out != want {
+		t.Fatalf("logs =\n%s\nwant\n%s", out, want)
+	}
+
+	// The active menu level overrides the handler one.
+	c.ActiveMenu().SetLogLevel(slog.LevelError)
+
+	out = captureStdout(t, func() {
+		logger.Warn("hidden")
+		logger.Error("shown")
+	})
+
+	if strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Fatalf("logs with the menu at error level =\n%s", out)
+	}
+}
@@ -0,0 +1,378 @@
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/reeflective/console/internal/jsonrpc"
+)
+
+// mcpProtocolVersion is the Model Context Protocol version used when
+// the client does not request one of the supported versions.
+const mcpProtocolVersion = "2025-06-18"
+
+// mcpProtocolVersions are the Model Context Protocol versions supported:
+// the tools used are the same in all of them.
+var mcpProtocolVersions = []string{mcpProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// ServeMCP serves the console commands as Model Context Protocol tools, reading
+// requests from in and writing responses to out (usually os.Stdin and os.Stdout),
+// until in is exhausted or the context is canceled.
+//
+// Every visible leaf command of every menu is a tool, named after its menu and
+// command path joined with underscores (for instance "client_info" for the info
+// command of the client menu, or "connect" for a command of the default menu).
+// Distinct commands with the same tool name are suffixed with their rank in menu
+// and command path order ("client_info_1", "client_info_2", etc).
+// Commands hidden, filtered by the filters active in their menu (see CommandFilterKey)
+// or not permitted to the console principal (see CommandRolesKey) are not exposed,
+// and are refused if called anyway. The tool input schema has
+// one property per command flag, and an "args" array for positional arguments.
+//
+// Tool calls are executed like Menu.Exec does, and the result is what the command
+// printed to its cobra outputs, marked as an error if the command failed. Since
+// out carries the protocol, commands must not print directly to os.Stdout.
+func (c *Console) ServeMCP(ctx context.Context, in io.Reader, out io.Writer) error {
+	server := jsonrpc.NewServer()
+
+	server.Handle("initialize", c.mcpInitialize)
+	server.Handle("ping", func(context.Context, json.RawMessage) (any, error) {
+		return struct{}{}, nil
+	})
+	server.Handle("notifications/initialized", func(context.Context, json.RawMessage) (any, error) {
+		return nil, nil
+	})
+	server.Handle("tools/list", func(context.Context, json.RawMessage) (any, error) {
+		return map[string]any{"tools": c.mcpTools()}, nil
+	})
+	server.Handle("tools/call", c.mcpCall)
+
+	return server.Serve(ctx, in, out)
+}
+
+// mcpTool is a console command exposed as an MCP tool.
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"inputSchema"`
+
+	menu  *Menu
+	path  []string
+	flags map[string]*pflag.Flag
+}
+
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type mcpResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError,omitempty"`
+}
+
+func (c *Console) mcpInitialize(_ context.Context, params json.RawMessage) (any, error) {
+	var req struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+
+	if err := jsonrpc.Decode(params, &req); err != nil {
+		return nil, err
+	}
+
+	// The client must disconnect if it does not support our version.
+	version := req.ProtocolVersion
+	if !slices.Contains(mcpProtocolVersions, version) {
+		version = mcpProtocolVersion
+	}
+
+	info := map[string]string{"name": c.name, "version": "(devel)"}
+	if build, ok := debug.ReadBuildInfo(); ok && build.Main.Version != "" {
+		info["version"] = build.Main.Version
+	}
+
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{"tools": map[string]any{}},
+		"serverInfo":      info,
+	}, nil
+}
+
+func (c *Console) mcpCall(ctx context.Context, params json.RawMessage) (any, error) {
+	var req struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+
+	if err := jsonrpc.Decode(params, &req); err != nil {
+		return nil, err
+	}
+
+	var tool *mcpTool
+
+	for _, candidate := range c.mcpTools() {
+		if candidate.Name == req.Name {
+			tool = &candidate
+			break
+		}
+	}
+
+	if tool == nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "unknown tool: %s", req.Name)
+	}
+
+	args, err := tool.args(req.Arguments)
+	if err != nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%s", err)
+	}
+
+	res := tool.menu.execArgs(ctx, args)
+
+	output := string(res.Stdout) + string(res.Stderr)
+	if res.Err != nil {
+		output += res.Err.Error()
+	}
+
+	return mcpResult{
+		Content: []mcpContent{{Type: "text", Text: output}},
+		IsError: res.Err != nil,
+	}, nil
+}
+
+// mcpTools returns the tools for all visible leaf commands of all menus.
+func (c *Console) mcpTools() []mcpTool {
+	tools := []mcpTool{}
+	principal := c.Principal()
+
+	for _, menu := range c.Menus() {
+		var walk func(cmd *cobra.Command, path []string)
+		walk = func(cmd *cobra.Command, path []string) {
+			for _, sub := range cmd.Commands() {
+				if sub.Hidden || sub.Name() == "help" || menu.checkPermitted(sub, nil, principal) != nil {
+					continue
+				}
+
+				subPath := append(append([]string(nil), path...), sub.Name())
+
+				if sub.HasAvailableSubCommands() {
+					walk(sub, subPath)
+				} else if sub.Runnable() {
+					tools = append(tools, newMCPTool(menu, sub, subPath))
+				}
+			}
+		}
+
+		walk(menu.newCommands(menu.activeFilters()), nil)
+	}
+
+	mcpDisambiguate(tools)
+
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name < tools[j].Name
+	})
+
+	return tools
+}
+
+// mcpDisambiguate renames the tools of distinct commands having the same name
+// (like the info command of the client menu and the client_info command of the
+// default menu), suffixing them with their rank in menu and command path order,
+// so that none of them is called instead of another.
+func mcpDisambiguate(tools []mcpTool) {
+	count := make(map[string]int)
+	for _, tool := range tools {
+		count[tool.Name]++
+	}
+
+	taken := make(map[string]bool)
+	for name, n := range count {
+		taken[name] = n == 1
+	}
+
+	for i, tool := range tools {
+		if count[tool.Name] == 1 {
+			continue
+		}
+
+		for rank := 1; ; rank++ {
+			name := tool.Name + "_" + strconv.Itoa(rank)
+			if !taken[name] && count[name] == 0 {
+				taken[name] = true
+				tools[i].Name = name
+
+				break
+			}
+		}
+	}
+}
+
+var mcpInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+func newMCPTool(menu *Menu, cmd *cobra.Command, path []string) mcpTool {
+	name := strings.Join(path, "_")
+	if menu.name != "" {
+		name = menu.name + "_" + name
+	}
+
+	tool := mcpTool{
+		Name:        mcpInvalidChars.ReplaceAllString(name, "_"),
+		Description: cmd.Short,
+		menu:        menu,
+		path:        path,
+		flags:       make(map[string]*pflag.Flag),
+	}
+
+	if cmd.Long != "" {
+		tool.Description = cmd.Long
+	}
+
+	if cmd.Example != "" {
+		tool.Description += "\n\nExamples:\n" + cmd.Example
+	}
+
+	properties := map[string]any{
+		"args": map[string]any{
+			"type":        "array",
+			"items":       map[string]any{"type": "string"},
+			"description": "Positional arguments: " + cmd.UseLine(),
+		},
+	}
+
+	var required []string
+
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || flag.Name == "help" {
+			return
+		}
+
+		schema := mcpFlagSchema(flag.Value.Type())
+		schema["description"] = flag.Usage
+
+		properties[flag.Name] = schema
+		tool.flags[flag.Name] = flag
+
+		if ann := flag.Annotations[cobra.BashCompOneRequiredFlag]; len(ann) > 0 && ann[0] == "true" {
+			required = append(required, flag.Name)
+		}
+	})
+
+	tool.InputSchema = map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	if len(required) > 0 {
+		tool.InputSchema["required"] = required
+	}
+
+	return tool
+}
+
+// mcpFlagSchema returns the JSON schema of a flag value, given its pflag type.
+func mcpFlagSchema(flagType string) map[string]any {
+	for _, suffix := range []string{"Slice", "Array"} {
+		if elem, found := strings.CutSuffix(flagType, suffix); found {
+			return map[string]any{"type": "array", "items": mcpFlagSchema(elem)}
+		}
+	}
+
+	switch flagType {
+	case "bool":
+		return map[string]any{"type": "boolean"}
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "count":
+		return map[string]any{"type": "integer"}
+	case "float32", "float64":
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+// args converts the arguments of a tool call into command-line arguments.
+func (t *mcpTool) args(arguments map[string]any) ([]string, error) {
+	args := append([]string(nil), t.path...)
+
+	names := make([]string, 0, len(arguments))
+	for name := range arguments {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var positional []string
+
+	for _, name := range names {
+		value := arguments[name]
+
+		if name == "args" {
+			values, err := mcpValues(value)
+			if err != nil {
+				return nil, fmt.Errorf("args: %w", err)
+			}
+
+			positional = values
+
+			continue
+		}
+
+		if _, found := t.flags[name]; !found {
+			return nil, fmt.Errorf("unknown argument: %s", name)
+		}
+
+		values, err := mcpValues(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		for _, val := range values {
+			args = append(args, "--"+name+"="+val)
+		}
+	}
+
+	if len(positional) > 0 {
+		args = append(args, "--")
+		args = append(args, positional...)
+	}
+
+	return args, nil
+}
+
+// mcpValues returns the command-line values of a JSON argument value.
+func mcpValues(value any) ([]string, error) {
+	switch val := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{val}, nil
+	case bool:
+		return []string{strconv.FormatBool(val)}, nil
+	case float64:
+		return []string{strconv.FormatFloat(val, 'f', -1, 64)}, nil
+	case []any:
+		var values []string
+
+		for _, elem := range val {
+			elemValues, err := mcpValues(elem)
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, elemValues...)
+		}
+
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %v", value)
+	}
+}
@@ -0,0 +1,191 @@
+package console
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestServeMCP(t *testing.T) {
+	c := New("test")
+	c.HideCommands("windows")
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+
+		connect := &cobra.Command{
+			Use:   "connect <host>",
+			Short: "Connect to a host",
+			Args:  cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				port, _ := cmd.Flags().GetInt("port")
+				tags, _ := cmd.Flags().GetStringSlice("tag")
+				cmd.Printf("%s:%d %v\n", args[0], port, tags)
+			},
+		}
+		connect.Flags().Int("port", 22, "port to use")
+		connect.Flags().StringSlice("tag", nil, "tags")
+		connect.Flags().Bool("verbose", false, "verbose output")
+		_ = connect.MarkFlagRequired("port")
+
+		group := &cobra.Command{Use: "config"}
+		group.AddCommand(&cobra.Command{Use: "show", Run: func(cmd *cobra.Command, _ []string) {}})
+
+		root.AddCommand(connect, group,
+			&cobra.Command{Use: "hidden", Hidden: true, Run: func(*cobra.Command, []string) {}},
+			&cobra.Command{
+				Use:         "win",
+				Annotations: map[string]string{CommandFilterKey: "windows"},
+				Run:         func(*cobra.Command, []string) {},
+			},
+		)
+
+		return root
+	})
+
+	c.NewMenu("client").SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{Use: "info", RunE: func(*cobra.Command, []string) error {
+			return exitError{3}
+		}})
+
+		return root
+	})
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"connect","arguments":{"port":2222,"tag":["a","b"],"args":["host"]}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"client_info"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"win"}}`,
+	}, "\n")
+
+	var out strings.Builder
+	if err := c.ServeMCP(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	type response struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code int `json:"code"`
+		} `json:"error"`
+	}
+
+	var responses []response
+
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var resp response
+
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("decode response %q: %v", line, err)
+		}
+
+		responses = append(responses, resp)
+	}
+
+	if len(responses) != 5 {
+		t.Fatalf("got %d responses, want 5:\n%s", len(responses), out.String())
+	}
+
+	// Initialization
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	_ = json.Unmarshal(responses[0].Result, &init)
+
+	if init.ProtocolVersion != "2025-03-26" {
+		t.Fatalf("protocol version = %q, want the requested one", init.ProtocolVersion)
+	}
+
+	// Tools: only visible and unfiltered leaf commands.
+	var list struct {
+		Tools []struct {
+			Name        string `json:"name"`
+			InputSchema struct {
+				Properties map[string]struct {
+					Type string `json:"type"`
+				} `json:"properties"`
+				Required []string `json:"required"`
+			} `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	_ = json.Unmarshal(responses[1].Result, &list)
+
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+	}
+
+	if !reflect_equal(names, []string{"client_info", "config_show", "connect"}) {
+		t.Fatalf("tools = %v, want [client_info config_show connect]", names)
+	}
+
+	schema := list.Tools[2].InputSchema
+	if schema.Properties["port"].Type != "integer" || schema.Properties["tag"].Type != "array" ||
+		schema.Properties["verbose"].Type != "boolean" || schema.Properties["args"].Type != "array" {
+		t.Fatalf("connect schema properties = %+v", schema.Properties)
+	}
+	if !reflect_equal(schema.Required, []string{"port"}) {
+		t.Fatalf("connect required flags = %v, want [port]", schema.Required)
+	}
+
+	// Calls
+	var result mcpResult
+
+	_ = json.Unmarshal(responses[2].Result, &result)
+	if result.IsError || len(result.Content) != 1 || result.Content[0].Text != "host:2222 [a b]\n" {
+		t.Fatalf("connect result = %+v", result)
+	}
+
+	_ = json.Unmarshal(responses[3].Result, &result)
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "exit status 3") {
+		t.Fatalf("client_info result = %+v, want an error", result)
+	}
+
+	if responses[4].Error == nil || responses[4].Error.Code != -32602 {
+		t.Fatalf("filtered tool call = %s, want an invalid params error", out.String())
+	}
+}
+
+func TestMCPToolCollisions(t *testing.T) {
+	c := New("test")
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(
+			&cobra.Command{Use: "client_info", Run: func(*cobra.Command, []string) {}},
+			&cobra.Command{Use: "client_info_1", Run: func(*cobra.Command, []string) {}},
+		)
+
+		return root
+	})
+
+	c.NewMenu("client").SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{Use: "info", Run: func(*cobra.Command, []string) {}})
+
+		return root
+	})
+
+	var names []string
+	for _, tool := range c.mcpTools() {
+		names = append(names, tool.Name+" "+tool.menu.Name()+"/"+strings.Join(tool.path, "/"))
+	}
+
+	want := []string{"client_info_1 /client_info_1", "client_info_2 /client_info", "client_info_3 client/info"}
+	if !reflect_equal(names, want) {
+		t.Fatalf("tools = %v, want %v", names, want)
+	}
+
+	// Unsupported protocol versions are not echoed.
+	res, _ := c.mcpInitialize(context.Background(), json.RawMessage(`{"protocolVersion":"1999-01-01"}`))
+	if version := res.(map[string]any)["protocolVersion"]; version != mcpProtocolVersion {
+		t.Fatalf("protocol version = %v, want %s", version, mcpProtocolVersion)
+	}
+}
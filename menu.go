@@ -1,9 +1,11 @@
 package console
 
 import (
-	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"os"
 	"strings"
 	"sync"
 
@@ -32,14 +34,15 @@ type Menu struct {
 	// Maps interrupt signals (CtrlC/IOF, etc) to specific error handlers.
 	interruptHandlers map[error]func(c *Console)
 
+	// OS signals trapped while commands run, and what to do with them.
+	signals        []os.Signal
+	signalHandlers map[os.Signal]signalHandler
+
 	// ErrorHandler is called when an error is encountered.
 	//
 	// If not set, the error is printed to the console on os.Stderr.
 	ErrorHandler ErrorHandler
 
-	// Input/output channels
-	out *bytes.Buffer
-
 	// The root cobra command/parser is the one returned by the handler provided
 	// through the `menu.SetCommands()` function. This command is thus renewed after
 	// each command invocation/execution.
@@ -50,9 +53,26 @@ type Menu struct {
 	// Command spawner
 	cmds Commands
 
+	// Commands and flags of the command tree hidden by the filters,
+	// shown again before hiding them anew if the tree is not regenerated.
+	hidden []command.Hidden
+
 	// An error template to use to produce errors when a command is unavailable.
 	errFilteredTemplate string
 
+	// Called instead of the root command when the first word is not a command.
+	notFoundHandler func(ctx context.Context, args []string) error
+
+	// Filters active in this menu only, in addition to the console ones.
+	filters []string
+
+	// Minimum level of the records printed by console log handlers.
+	logLevel slog.Leveler
+
+	// Messages printed while the menu was not active (guarded by mutex).
+	messages     []Message
+	messageLimit int
+
 	// History sources peculiar to this menu.
 	historyNames []string
 	histories    map[string]readline.History
@@ -73,15 +93,16 @@ func newMenu(name string, console *Console) *Menu {
 		console:           console,
 		name:              name,
 		Command:           &cobra.Command{},
-		out:               bytes.NewBuffer(nil),
+		messageLimit:      defaultMessageLimit,
 		interruptHandlers: make(map[error]func(c *Console)),
+		signalHandlers:    make(map[os.Signal]signalHandler),
 		histories:         make(map[string]readline.History),
 		mutex:             &sync.RWMutex{},
 		ErrorHandler:      defaultErrorHandler,
 	}
 
     // Prompt setup
-    prompt := (ui.NewPrompt(console.name, name, menu.out))
+    prompt := (ui.NewPrompt(console.name, name, console.unreadMessages))
 	menu.prompt = (*Prompt)(prompt)
 
 	// Add a default in memory history to each menu
@@ -235,8 +256,8 @@ func (m *Menu) DeleteHistorySource(name string) {
 }
 
 // TransientPrintf prints a message to the console, but only if the current
-// menu is active. If the menu is not active, the message is buffered and will
-// be printed the next time the menu is active.
+// menu is active. If the menu is not active, the message is queued, and will
+// be printed the next time the menu is active (see Menu.Messages).
 //
 // The message is printed as a transient message, meaning that it will be
 // printed above the current prompt, effectively "pushing" the prompt down.
@@ -245,25 +266,18 @@ func (m *Menu) DeleteHistorySource(name string) {
 // will simply print the log below the current line, and will not print
 // the prompt. In any other case this function will work normally.
 func (m *Menu) TransientPrintf(msg string, args ...any) (n int, err error) {
-	n, err = fmt.Fprintf(m.out, msg, args...)
-	if err != nil {
-		return
-	}
+	text := fmt.Sprintf(msg, args...)
 
-	if !m.active {
-		fmt.Fprintf(m.out, "\n")
-		return
+	if m.queue(slog.LevelInfo, strings.TrimRight(text, "\n")) {
+		return len(text), nil
 	}
 
-	buf := m.out.String()
-	m.out.Reset()
-
-	return m.console.TransientPrintf("%s", buf)
+	return m.console.TransientPrintf("%s", text)
 }
 
 // Printf prints a message to the console, but only if the current menu
-// is active. If the menu is not active, the message is buffered and will
-// be printed the next time the menu is active.
+// is active. If the menu is not active, the message is queued, and will
+// be printed the next time the menu is active (see Menu.Messages).
 //
 // Unlike TransientPrintf, this function will not print the message above
 // the current prompt, but will instead print it below it.
@@ -272,33 +286,45 @@ func (m *Menu) TransientPrintf(msg string, args ...any) (n int, err error) {
 // will simply print the log below the current line, and will not print
 // the prompt. In any other case this function will work normally.
 func (m *Menu) Printf(msg string, args ...any) (n int, err error) {
-	n, err = fmt.Fprintf(m.out, msg, args...)
-	if err != nil {
-		return
-	}
+	text := fmt.Sprintf(msg, args...)
 
-	if !m.active {
-		fmt.Fprintf(m.out, "\n")
-		return
+	if m.queue(slog.LevelInfo, strings.TrimRight(text, "\n")) {
+		return len(text), nil
 	}
 
-	buf := m.out.String()
-	m.out.Reset()
-
-	return m.console.Printf("%s", buf)
+	return m.console.Printf("%s", text)
 }
 
 // CheckIsAvailable checks if a target command is marked as filtered
 // by the console application registered/and or active filters (added
-// with console.Hide/ShowCommand()).
+// with console.Hide/ShowCommand(), or with the menu ones).
 // If filtered, returns a template-formatted error message showing the
-// list of incompatible filters. If not filtered, no error is returned.
+// list of incompatible filters. If the console principal does not hold
+// the roles required by the command, returns a PermissionError.
+// Otherwise, no error is returned.
 func (m *Menu) CheckIsAvailable(cmd *cobra.Command) error {
+	if err := m.checkAvailable(cmd, m.activeFilters()); err != nil {
+		return err
+	}
+
+	return m.checkPermitted(cmd, nil, m.console.Principal())
+}
+
+// ActiveFiltersFor returns all the active menu filters that a given command
+// does not declare as compliant with (added with console.Hide/ShowCommand(),
+// or with the menu ones): the filters active in the menu are both the console
+// filters and those activated with Menu.HideCommands.
+func (m *Menu) ActiveFiltersFor(cmd *cobra.Command) []string {
+	return command.ActiveFilters(cmd, m.activeFilters())
+}
+
+// checkAvailable checks if a command is filtered by any of the given filters.
+func (m *Menu) checkAvailable(cmd *cobra.Command, activeFilters []string) error {
 	if cmd == nil {
 		return nil
 	}
 
-	filters := m.ActiveFiltersFor(cmd)
+	filters := command.ActiveFilters(cmd, activeFilters)
 	if len(filters) == 0 {
 		return nil
 	}
@@ -309,9 +335,10 @@ func (m *Menu) CheckIsAvailable(cmd *cobra.Command) error {
 
 
 	err := strutil.Template(&bufErr, errTemplate, map[string]interface{}{
-		"menu":    m,
-		"cmd":     cmd,
-		"filters": filters,
+		"menu":       m,
+		"cmd":        cmd,
+		"filters":    filters,
+		"expression": command.FilterExpression(cmd, activeFilters),
 	})
 	if err != nil {
 		return err
@@ -320,27 +347,54 @@ func (m *Menu) CheckIsAvailable(cmd *cobra.Command) error {
 	return errors.New(bufErr.String())
 }
 
-// ActiveFiltersFor returns all the active menu filters that a given command
-// does not declare as compliant with (added with console.Hide/ShowCommand()).
-func (m *Menu) ActiveFiltersFor(cmd *cobra.Command) []string {
-	// Snapshot the console filters once under a read lock, then walk the
-	// command tree lock-free. The previous version held a write lock and
-	// recursed into itself while holding it, which both serialized every
-	// completion/highlight render and risked a self-deadlock on the
-	// (non-reentrant) mutex whenever the parent-subtree branch was taken.
-	m.console.mutex.RLock()
-	consoleFilters := append([]string(nil), m.console.filters...)
-	m.console.mutex.RUnlock()
+// checkFlagsAvailable checks if any of the flags set in args (the arguments
+// following the command path) is filtered by any of the given filters.
+func (m *Menu) checkFlagsAvailable(cmd *cobra.Command, args []string, activeFilters []string) error {
+	used := command.UsedFilteredFlags(cmd, args, activeFilters)
+	if len(used) == 0 {
+		return nil
+	}
 
-	return command.ActiveFilters(cmd, consoleFilters)
+	flag := used[0]
+
+	return fmt.Errorf("flag --%s of command %s is unavailable, hidden by filter expression %q",
+		flag.Name, cmd.Name(), command.FlagFilterExpression(flag))
 }
 
 // SetErrFilteredCommandTemplate sets the error template to be used
 // when a called command can't be executed because it's mark filtered.
+// The template data has the menu (.menu), the command (.cmd), the
+// filter expression hiding it (.expression) and its true terms (.filters).
 func (m *Menu) SetErrFilteredCommandTemplate(s string) {
 	m.errFilteredTemplate = s
 }
 
+// SetNotFoundHandler sets a handler called when the first word of a command
+// line is not a command of the menu, with the context of the execution and all
+// the words of the line. This can be used to forward the line to the system
+// shell, to try a plugin binary, or to run an implicit default command.
+//
+// The handler runs like commands do: after the console pre-run hooks, and
+// its context is canceled on interrupt signals. Its error is returned as
+// the execution error. Without handler (or if set to nil), an unknown
+// command produces an UnknownCommandError.
+//
+// Note that the handler is called even if the menu root command accepts
+// arguments: it is then never run with a first word that is not a command.
+func (m *Menu) SetNotFoundHandler(handler func(ctx context.Context, args []string) error) {
+	m.mutex.Lock()
+	m.notFoundHandler = handler
+	m.mutex.Unlock()
+}
+
+// notFound returns the handler for unknown commands, if any.
+func (m *Menu) notFound() func(ctx context.Context, args []string) error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.notFoundHandler
+}
+
 // resetPreRun is called before each new read line loop and before arbitrary RunCommand() calls.
 // This function is responsible for resetting the menu state to a clean state, regenerating the
 // menu commands, and ensuring that the correct prompt is bound to the shell.
@@ -351,9 +405,6 @@ func (m *Menu) resetPreRun() {
 	// Commands
 	m.regenerate()
 
-	// Reset or adjust any buffered command output.
-	m.resetCmdOutput()
-
 	// Prompt binding
 	prompt := (*ui.Prompt)(m.Prompt())
 	ui.BindPrompt(prompt, m.console.shell)
@@ -375,43 +426,87 @@ func (m *Menu) resetCommands() {
 func (m *Menu) regenerate() {
 	if m.cmds != nil {
 		m.Command = m.cmds()
+	} else {
+		// The same tree is filtered again, maybe with other filters.
+		for _, hidden := range m.hidden {
+			hidden.Show()
+		}
 	}
 
 	if m.Command == nil {
-		m.Command = &cobra.Command{
-			Annotations: make(map[string]string),
-		}
+		m.Command = newRootCommand()
 	}
 
+	command.AddConfirmFlags(m.Command)
+
 	// Hide commands that are not available.
-	m.hideFilteredCommands(m.Command)
+	m.hidden = m.hideFilteredCommands(m.Command)
 
 	// The command tree just changed, so any memoized highlight is now stale.
 	m.console.hlCache.Store(nil)
 }
 
-// hide commands that are filtered so that they are not
-// shown in the help strings or proposed as completions.
-func (m *Menu) hideFilteredCommands(root *cobra.Command) {
-	m.console.mutex.RLock()
-	consoleFilters := append([]string(nil), m.console.filters...)
-	m.console.mutex.RUnlock()
+// CommandTree returns a new command tree for the menu, as generated by its
+// commands function, without hiding commands filtered by the console: this
+// is meant for tools walking the menu commands, like documentation generators.
+// If the menu has no command generator, this returns a copy of its command
+// tree, in which the commands hidden by filters are visible (see newCommands).
+// Filters are not evaluated at all, so that commands shown when a filter is
+// inactive (like those with a "!filter" expression) are visible too.
+func (m *Menu) CommandTree() *cobra.Command {
+	m.mutex.RLock()
+
+	var root *cobra.Command
+
+	switch {
+	case m.cmds != nil:
+		root = m.cmds()
+	case m.Command != nil:
+		root = command.Clone(m.Command, m.hidden...)
+	}
+
+	m.mutex.RUnlock()
+
+	if root == nil {
+		root = newRootCommand()
+	}
+
+	command.AddConfirmFlags(root)
+
+	return root
+}
+
+// newCommands returns a command tree for the menu, hiding commands filtered
+// by the given filters, without touching the command tree bound to the menu.
+// If the menu has no command generator, this is a copy of its command tree,
+// which shares its flag values and thus must not be executed.
+func (m *Menu) newCommands(filters []string) *cobra.Command {
+	root := m.CommandTree()
+	command.HideFiltered(root, filters)
 
-	command.HideFiltered(root, consoleFilters)
+	return root
 }
 
-func (m *Menu) resetCmdOutput() {
-	buf := strings.TrimSpace(m.out.String())
+// newRootCommand returns the root command of menus without commands.
+func newRootCommand() *cobra.Command {
+	return &cobra.Command{
+		Annotations: make(map[string]string),
+	}
+}
+
+// hide commands that are filtered, or not permitted to the console principal,
+// so that they are not shown in the help strings or proposed as completions.
+// It returns the commands and flags hidden. It assumes m.mutex is held.
+func (m *Menu) hideFilteredCommands(root *cobra.Command) []command.Hidden {
+	hidden := []command.Hidden{
+		command.HideFiltered(root, addFilters(m.console.activeFilters(), m.filters...)),
+	}
 
-	// If our command has printed everything to stdout, nothing to do.
-	if len(buf) == 0 {
-		m.out.Reset()
-		return
+	if principal := m.console.Principal(); principal != nil {
+		hidden = append(hidden, command.HideUnpermitted(root, principal.Roles))
 	}
 
-	// Add two newlines to the end of the buffer, so that the
-	// next command will be printed slightly below the current one.
-	m.out.WriteString("\n")
+	return hidden
 }
 
 func (m *Menu) defaultHistoryName() string {
@@ -429,6 +524,6 @@ func (m *Menu) errorFilteredCommandTemplate(filters []string) string {
 		return m.errFilteredTemplate
 	}
 
-	return `Command {{.cmd.Name}} is only available for: {{range .filters }}
-    - {{.}} {{end}}`
+	return `Command {{.cmd.Name}} is unavailable, hidden by filter expression "{{.expression}}":{{range .filters }}
+    - {{.}}{{end}}`
 }
@@ -0,0 +1,158 @@
+package console
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// defaultMessageLimit is the default maximum number of messages held by a menu.
+const defaultMessageLimit = 100
+
+// Message is a message printed to a menu (with Menu.Printf, Menu.Notify, its
+// writers, etc) while it was not the active menu, and held in its queue until
+// it is cleared. Messages are replayed when the menu becomes active again.
+type Message struct {
+	Time  time.Time  // Time at which the message was printed.
+	Level slog.Level // Severity of the message, slog.LevelInfo unless printed with Notify.
+	Text  string     // Message, without trailing newlines.
+	Read  bool       // Whether the message was replayed since it was queued.
+}
+
+// String returns the message as it is replayed.
+func (msg Message) String() string {
+	text := msg.Time.Format(time.TimeOnly) + " "
+
+	if msg.Level != slog.LevelInfo {
+		text += msg.Level.String() + " "
+	}
+
+	return text + msg.Text
+}
+
+// Notify prints a message with the given severity, like Printf does: it is
+// printed right away if the menu is active, or queued until it is otherwise.
+func (m *Menu) Notify(level slog.Level, msg string, args ...any) {
+	text := strings.TrimRight(fmt.Sprintf(msg, args...), "\n")
+
+	if !m.queue(level, text) {
+		m.console.printLine(text)
+	}
+}
+
+// Messages returns the messages held by the menu, oldest first.
+func (m *Menu) Messages() []Message {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return append([]Message(nil), m.messages...)
+}
+
+// ReadMessages is like Messages, but marks all messages as read.
+func (m *Menu) ReadMessages() []Message {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	messages := append([]Message(nil), m.messages...)
+
+	for i := range m.messages {
+		m.messages[i].Read = true
+	}
+
+	return messages
+}
+
+// UnreadMessages returns the number of messages held by the
+// menu that were not replayed yet (see Message.Read).
+func (m *Menu) UnreadMessages() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	unread := 0
+
+	for _, msg := range m.messages {
+		if !msg.Read {
+			unread++
+		}
+	}
+
+	return unread
+}
+
+// ClearMessages removes all messages held by the menu.
+func (m *Menu) ClearMessages() {
+	m.mutex.Lock()
+	m.messages = nil
+	m.mutex.Unlock()
+}
+
+// SetMessageLimit sets the maximum number of messages held by the menu
+// (100 by default): when it is reached, the oldest messages are dropped.
+// With a zero or negative limit, no messages are held: those printed while
+// the menu is not active are dropped.
+func (m *Menu) SetMessageLimit(limit int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messageLimit = max(limit, 0)
+	m.trimMessages()
+}
+
+// queue holds a message if the menu is not active, and returns true if it did.
+func (m *Menu) queue(level slog.Level, text string) bool {
+	if m.console.activeMenu() == m {
+		return false
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = append(m.messages, Message{Time: time.Now(), Level: level, Text: text})
+	m.trimMessages()
+
+	return true
+}
+
+// trimMessages drops the oldest messages above the limit.
+// It assumes m.mutex is held.
+func (m *Menu) trimMessages() {
+	if len(m.messages) > m.messageLimit {
+		m.messages = append([]Message(nil), m.messages[len(m.messages)-m.messageLimit:]...)
+	}
+}
+
+// replayMessages prints the unread messages held by the menu, marking them read.
+func (m *Menu) replayMessages() {
+	m.mutex.Lock()
+
+	var unread []Message
+
+	for i := range m.messages {
+		if !m.messages[i].Read {
+			unread = append(unread, m.messages[i])
+			m.messages[i].Read = true
+		}
+	}
+
+	m.mutex.Unlock()
+
+	for _, msg := range unread {
+		m.console.printLine(msg.String())
+	}
+}
+
+// unreadMessages returns the number of unread messages in all menus but
+// the active one, which is shown in the default prompt of the menus.
+func (c *Console) unreadMessages() int {
+	active := c.activeMenu()
+	unread := 0
+
+	for _, menu := range c.Menus() {
+		if menu != active {
+			unread += menu.UnreadMessages()
+		}
+	}
+
+	return unread
+}
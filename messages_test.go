@@ -0,0 +1,94 @@
+package console
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestMessages(t *testing.T) {
+	c := New("test")
+	c.isExecuting.Store(true)
+
+	client := c.NewMenu("client")
+	client.SetMessageLimit(2)
+
+	out := captureStdout(t, func() {
+		client.Printf("dropped\n")
+		client.Notify(slog.LevelWarn, "session %d closed", 1)
+		client.TransientPrintf("new session\n")
+	})
+
+	if out != "" {
+		t.Fatalf("inactive menu printed %q, want nothing", out)
+	}
+
+	messages := client.Messages()
+	if len(messages) != 2 || messages[0].Text != "session 1 closed" || messages[1].Text != "new session" {
+		t.Fatalf("messages = %+v, want the last two", messages)
+	}
+
+	if messages[0].Level != slog.LevelWarn || messages[1].Level != slog.LevelInfo {
+		t.Fatalf("levels = %v, %v, want WARN, INFO", messages[0].Level, messages[1].Level)
+	}
+
+	if got := c.unreadMessages(); got != 2 {
+		t.Fatalf("unread messages = %d, want 2", got)
+	}
+
+	out = captureStdout(t, func() {
+		c.SwitchMenu("client")
+		client.Printf("direct\n")
+	})
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], " WARN session 1 closed") ||
+		!strings.HasSuffix(lines[1], " new session") || lines[2] != "direct" {
+		t.Fatalf("replayed %q, want the queued messages then the direct one", out)
+	}
+
+	if client.UnreadMessages() != 0 || len(client.Messages()) != 2 {
+		t.Fatal("replayed messages should be kept, marked read")
+	}
+
+	// Messages are replayed only once.
+	out = captureStdout(t, func() {
+		c.SwitchMenu("")
+		c.SwitchMenu("client")
+	})
+
+	if out != "" {
+		t.Fatalf("second switch replayed %q, want nothing", out)
+	}
+
+	client.ClearMessages()
+
+	if len(client.Messages()) != 0 {
+		t.Fatal("ClearMessages should remove all messages")
+	}
+}
+
+func TestMessageLimitNegative(t *testing.T) {
+	c := New("test")
+	c.isExecuting.Store(true)
+
+	client := c.NewMenu("client")
+
+	captureStdout(t, func() {
+		client.Printf("held\n")
+	})
+
+	client.SetMessageLimit(-1)
+
+	if messages := client.Messages(); len(messages) != 0 {
+		t.Fatalf("messages = %+v, want none", messages)
+	}
+
+	captureStdout(t, func() {
+		client.Printf("dropped\n")
+	})
+
+	if messages := client.Messages(); len(messages) != 0 {
+		t.Fatalf("messages = %+v, want none", messages)
+	}
+}
@@ -0,0 +1,119 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/reeflective/readline"
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console/internal/command"
+)
+
+// maxMissingArgs is the maximum number of positional arguments asked for.
+const maxMissingArgs = 8
+
+// promptMissing asks the user for the required flags and positional arguments
+// missing from the command line of the target command, when the console is set
+// to (see Console.PromptMissing), and adds the answers to the execution args.
+func (c *Console) promptMissing(ctx context.Context, exec *execution, target *cobra.Command, args []string) error {
+	if !c.PromptMissing || !interactive(ctx) || target == nil || !target.Runnable() {
+		return nil
+	}
+
+	flags := command.MissingRequiredFlags(target, args)
+	positional := command.PositionalArgs(target, args)
+	missing := missingArgs(target, positional)
+
+	if len(flags) == 0 && missing == 0 {
+		return nil
+	}
+
+	// Flags are added before the end of flags (--), if any.
+	dash := slices.Index(exec.args, "--")
+	if dash == -1 {
+		dash = len(exec.args)
+	}
+
+	head := append([]string(nil), exec.args[:dash]...)
+	tail := append([]string(nil), exec.args[dash:]...)
+
+	// Candidates are the completions of the item in the command line, computed
+	// with a new tree each time, so that the one about to be executed is untouched.
+	// Menus without a generator are completed with a copy of their bound tree: its
+	// commands are not modified, but the copied flags share their values with it,
+	// so the flags of the line are parsed again into the command about to run.
+	complete := func(prefix string) func([]rune, int) readline.Completions {
+		return func(answer []rune, cursor int) readline.Completions {
+			input := []rune(prefix + string(answer[:cursor]))
+			tree := exec.menu.newCommands(exec.filters)
+
+			return c.shellCompletions(exec.menu.completeTree(tree, input, len(input)))
+		}
+	}
+
+	for _, flag := range flags {
+		prompt := fmt.Sprintf("--%s (%s): ", flag.Name, flag.Usage)
+
+		value, err := c.askRequired(ctx, prompt, complete(shellquote.Join(head...)+" --"+flag.Name+" "))
+		if err != nil {
+			return err
+		}
+
+		head = append(head, "--"+flag.Name+"="+value)
+	}
+
+	for i := range missing {
+		prompt := fmt.Sprintf("%s argument %d: ", commandPath(target), len(positional)+i+1)
+		line := shellquote.Join(append(slices.Clone(head), tail...)...) + " "
+
+		value, err := c.askRequired(ctx, prompt, complete(line))
+		if err != nil {
+			return err
+		}
+
+		tail = append(tail, value)
+	}
+
+	exec.args = append(head, tail...)
+
+	return nil
+}
+
+// askRequired asks for a value until the answer is not empty.
+func (c *Console) askRequired(ctx context.Context, prompt string, completer func([]rune, int) readline.Completions) (string, error) {
+	for {
+		answer, err := c.readDialog(ctx, dialog{prompt: prompt, completer: completer})
+		if err != nil {
+			return "", err
+		}
+
+		if answer = strings.TrimSpace(answer); answer != "" {
+			return answer, nil
+		}
+	}
+}
+
+// missingArgs returns the number of positional arguments to add to
+// the given ones for the command to accept them, or 0 if it already
+// does, or if no number of arguments would make it accept them.
+func missingArgs(cmd *cobra.Command, positional []string) int {
+	if cmd.ValidateArgs(positional) == nil {
+		return 0
+	}
+
+	args := slices.Clone(positional)
+
+	for missing := 1; missing <= maxMissingArgs; missing++ {
+		args = append(args, "_")
+
+		if cmd.ValidateArgs(args) == nil {
+			return missing
+		}
+	}
+
+	return 0
+}
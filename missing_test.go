@@ -0,0 +1,62 @@
+package console
+
+import (
+	"context"
+	"testing"
+
+	"github.com/carapace-sh/carapace"
+	"github.com/spf13/cobra"
+)
+
+func TestPromptMissing(t *testing.T) {
+	c := New("test")
+	c.PromptMissing = true
+
+	var host, port string
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		connect := &cobra.Command{
+			Use:  "connect <host>",
+			Args: cobra.ExactArgs(1),
+			Run: func(cmd *cobra.Command, args []string) {
+				host = args[0]
+				port, _ = cmd.Flags().GetString("port")
+			},
+		}
+
+		connect.Flags().String("port", "", "port to connect to")
+		_ = connect.MarkFlagRequired("port")
+
+		root.AddCommand(connect)
+
+		comps := carapace.Gen(connect)
+		comps.FlagCompletion(carapace.ActionMap{"port": carapace.ActionValues("8080", "9090")})
+		comps.PositionalCompletion(carapace.ActionValues("alpha", "beta"))
+
+		return root
+	})
+
+	// The flag and argument are asked for, with their completions.
+	c.Shell().Keys.Feed(false, []rune("connect\r80\t\ral\t\r")...)
+
+	if err := c.RunOnce(context.Background()); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	if host != "alpha" || port != "8080" {
+		t.Fatalf("connect ran with host %q and port %q, want alpha and 8080", host, port)
+	}
+
+	// Nothing is asked if the line is complete.
+	c.Shell().Keys.Feed(false, []rune("connect beta --port 1\r")...)
+
+	if err := c.RunOnce(context.Background()); err != nil || host != "beta" || port != "1" {
+		t.Fatalf("connect = %v, with host %q and port %q, want beta and 1", err, host, port)
+	}
+
+	// Nor when the user can't be asked.
+	if _, err := c.ActiveMenu().Exec(context.Background(), "connect"); err == nil {
+		t.Fatal("Exec connect should fail with missing arguments")
+	}
+}
@@ -0,0 +1,58 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNotFoundHandler(t *testing.T) {
+	c := newSuggestConsole()
+	menu := c.ActiveMenu()
+
+	var got []string
+
+	menu.SetNotFoundHandler(func(ctx context.Context, args []string) error {
+		if ctx.Done() == nil {
+			t.Error("handler context is not cancelable")
+		}
+
+		got = args
+
+		return exitError{127}
+	})
+
+	err := menu.RunCommandLine(context.Background(), "ls -l /tmp")
+	if !reflect_equal(got, []string{"ls", "-l", "/tmp"}) {
+		t.Fatalf("handler args = %v, want [ls -l /tmp]", got)
+	}
+	if ExitCode(err) != 127 || c.ExitStatus() != 127 {
+		t.Fatalf("error = %v, status %d, want the handler exit code 127", err, c.ExitStatus())
+	}
+
+	// Commands, and unknown subcommands, are not handled.
+	got = nil
+
+	if err := menu.RunCommandLine(context.Background(), "connect"); err != nil || got != nil {
+		t.Fatalf("connect: error %v, handler args %v", err, got)
+	}
+
+	var unknown UnknownCommandError
+	if err := menu.RunCommandLine(context.Background(), "config shwo"); !errors.As(err, &unknown) || got != nil {
+		t.Fatalf("config shwo: error %v, handler args %v, want an unknown command error", err, got)
+	}
+
+	// Flag values are not command words.
+	for _, line := range []string{"--format json", "config --format json"} {
+		if err := menu.RunCommandLine(context.Background(), line); err != nil || got != nil {
+			t.Fatalf("%s: error %v, handler args %v", line, err, got)
+		}
+	}
+
+	// Without handler, unknown commands are errors again.
+	menu.SetNotFoundHandler(nil)
+
+	if err := menu.RunCommandLine(context.Background(), "ls"); !errors.As(err, &unknown) {
+		t.Fatalf("ls: error %v, want an unknown command error", err)
+	}
+}
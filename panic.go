@@ -0,0 +1,42 @@
+package console
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of a command, hook or completer which panicked.
+// The console recovers from such panics, so that it keeps running with its
+// menus, histories and sessions: like other errors, a PanicError is given to
+// the menu ErrorHandler, and is returned by the execution functions.
+// Panics in goroutines started by commands or completions can't be recovered,
+// and still crash the program: this includes carapace actions run in parallel
+// (see carapace.Batch) or with a timeout (see carapace.Action.Timeout).
+type PanicError struct {
+	Source string // What panicked: "command", "hook", "line hook" or "completer".
+	Value  any    // Value given to panic.
+	Stack  []byte // Stack trace of the goroutine which panicked.
+}
+
+// Error implements the error interface.
+func (e PanicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.Source, e.Value)
+}
+
+// Unwrap returns the value given to panic if it is an error.
+func (e PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanic recovers from a panic, replacing *err with a PanicError.
+// It must be deferred by the function whose panics are recovered.
+func recoverPanic(source string, err *error) {
+	if value := recover(); value != nil {
+		*err = PanicError{
+			Source: source,
+			Value:  value,
+			Stack:  debug.Stack(),
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestPanicRecovery(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	var handled []error
+
+	menu.ErrorHandler = func(err error) error {
+		handled = append(handled, err)
+		return nil
+	}
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(
+			&cobra.Command{
+				Use: "boom",
+				Run: func(*cobra.Command, []string) { panic("boom") },
+				ValidArgsFunction: func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+					panic("no completions")
+				},
+			},
+			&cobra.Command{
+				Use:  "eof",
+				RunE: func(*cobra.Command, []string) error { panic(io.EOF) },
+			},
+			&cobra.Command{Use: "ok", Run: func(*cobra.Command, []string) {}},
+		)
+
+		return root
+	})
+
+	// Commands, read by the shell: the loop goes on.
+	c.Shell().Keys.Feed(false, []rune("boom\r")...)
+
+	err := c.RunOnce(context.Background())
+
+	var panicErr PanicError
+	if !errors.As(err, &panicErr) || panicErr.Source != "command" || panicErr.Value != "boom" {
+		t.Fatalf("boom error = %v, want a command PanicError", err)
+	}
+
+	if !strings.Contains(string(panicErr.Stack), "TestPanicRecovery") {
+		t.Fatalf("panic stack does not show the command:\n%s", panicErr.Stack)
+	}
+
+	if len(handled) != 1 || !errors.As(handled[0], &panicErr) {
+		t.Fatalf("handled errors = %v, want the PanicError", handled)
+	}
+
+	c.Shell().Keys.Feed(false, []rune("ok\r")...)
+
+	if err := c.RunOnce(context.Background()); err != nil {
+		t.Fatalf("ok after a panic: %v", err)
+	}
+
+	// Panics with errors can be matched against them.
+	if _, err := menu.Exec(context.Background(), "eof"); !errors.Is(err, io.EOF) {
+		t.Fatalf("eof error = %v, want io.EOF", err)
+	}
+
+	// Completions.
+	handled = nil
+
+	comps := menu.Complete("boom ", -1)
+	if len(comps.Values) != 0 || len(comps.Messages) != 1 || comps.Messages[0] != "completer panicked: no completions" {
+		t.Fatalf("completions = %+v, want the panic message", comps)
+	}
+
+	if len(handled) != 1 || !errors.As(handled[0], &panicErr) || panicErr.Source != "completer" {
+		t.Fatalf("handled errors = %v, want the completer PanicError", handled)
+	}
+
+	if comps := menu.Complete("", -1); len(comps.Values) != 3 || len(comps.Messages) != 0 {
+		t.Fatalf("completions after a panic = %+v, want the commands", comps)
+	}
+}
+
+func TestHookPanicRecovery(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+	menu.ErrorHandler = func(error) error { return nil }
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{Use: "ok", Run: func(*cobra.Command, []string) {}})
+
+		return root
+	})
+
+	hooks := []struct {
+		name   string
+		source string
+		set    func(panicking bool)
+	}{
+		{"pre-read", "hook", func(panicking bool) {
+			c.PreReadlineHooks = nil
+			if panicking {
+				c.PreReadlineHooks = []func() error{func() error { panic("pre-read") }}
+			}
+		}},
+		{"line", "line hook", func(panicking bool) {
+			c.PreCmdRunLineHooks = nil
+			if panicking {
+				c.PreCmdRunLineHooks = []func([]string) ([]string, error){func([]string) ([]string, error) { panic("line") }}
+			}
+		}},
+		{"pre-run", "hook", func(panicking bool) {
+			c.PreCmdRunHooks = nil
+			if panicking {
+				c.PreCmdRunHooks = []func() error{func() error { panic("pre-run") }}
+			}
+		}},
+		{"post-run", "hook", func(panicking bool) {
+			c.PostCmdRunHooks = nil
+			if panicking {
+				c.PostCmdRunHooks = []func() error{func() error { panic("post-run") }}
+			}
+		}},
+	}
+
+	for _, hook := range hooks {
+		hook.set(true)
+
+		err := c.RunScript(context.Background(), strings.NewReader("ok\n"))
+
+		var panicErr PanicError
+		if !errors.As(err, &panicErr) || panicErr.Source != hook.source || panicErr.Value != hook.name {
+			t.Fatalf("%s hook error = %v, want a PanicError", hook.name, err)
+		}
+
+		hook.set(false)
+
+		if err := c.RunScript(context.Background(), strings.NewReader("ok\n")); err != nil {
+			t.Fatalf("after a %s hook panic: %v", hook.name, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/reeflective/console/internal/command"
+)
+
+// CommandRolesKey should be used as a key in a cobra.Annotation map. The value
+// is a comma-separated list of roles, the principal of the console needing at
+// least one of them to run the command. Roles declared on parent commands apply
+// to their subcommands as well:
+//
+//	cmd.Annotations = map[string]string{console.CommandRolesKey: "admin,operator"}
+//
+// Commands not permitted are hidden from help and completions, and refused at
+// execution with a PermissionError. When no principal is set (the default),
+// roles are not checked at all.
+const CommandRolesKey = command.RolesKey
+
+type (
+	// Principal is the identity on behalf of which commands are run, and the
+	// roles it holds, checked against those required by commands.
+	Principal struct {
+		Name  string
+		Roles []string
+	}
+
+	// PermissionError is returned when the principal does not hold
+	// any of the roles required to run a command.
+	PermissionError struct {
+		Command   string    // Path of the command (without the menu root).
+		Menu      string    // Name of the menu in which the command is.
+		Args      []string  // Arguments of the command line, including the command path.
+		Principal Principal // Principal on behalf of which the command was run.
+		Required  []string  // Roles required by the command, any of which is enough.
+	}
+)
+
+// Error implements the error interface.
+func (e PermissionError) Error() string {
+	who := ""
+	if e.Principal.Name != "" {
+		who = fmt.Sprintf(" for %q", e.Principal.Name)
+	}
+
+	return fmt.Sprintf("permission denied%s: command %s requires one of the roles: %s",
+		who, e.Command, strings.Join(e.Required, ", "))
+}
+
+// ExitCode returns 126, like shells do for commands that cannot be executed.
+func (e PermissionError) ExitCode() int {
+	return 126
+}
+
+// SetPrincipal sets the principal on behalf of which commands are run, whose
+// roles are checked against those required by commands (see CommandRolesKey).
+// A nil principal disables permission checks.
+func (c *Console) SetPrincipal(principal *Principal) {
+	c.mutex.Lock()
+	c.principal = principal
+	c.mutex.Unlock()
+}
+
+// Principal returns the principal set with SetPrincipal, or nil if none is.
+func (c *Console) Principal() *Principal {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.principal
+}
+
+// SetPrincipal sets the principal on behalf of which commands of this session
+// are run. A session without principal uses the console one.
+func (s *Session) SetPrincipal(principal *Principal) {
+	s.mutex.Lock()
+	s.principal = principal
+	s.mutex.Unlock()
+}
+
+// Principal returns the principal of the session, or the console one
+// if the session has none.
+func (s *Session) Principal() *Principal {
+	s.mutex.RLock()
+	principal := s.principal
+	s.mutex.RUnlock()
+
+	if principal == nil {
+		return s.console.Principal()
+	}
+
+	return principal
+}
+
+// checkPermitted checks the roles required by a command against those of the principal.
+func (m *Menu) checkPermitted(cmd *cobra.Command, args []string, principal *Principal) error {
+	if cmd == nil || principal == nil {
+		return nil
+	}
+
+	missing := command.MissingRoles(cmd, principal.Roles)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return PermissionError{
+		Command:   commandPath(cmd),
+		Menu:      m.name,
+		Args:      args,
+		Principal: *principal,
+		Required:  missing,
+	}
+}
+
+// auditDenied passes a denied execution to the console audit callback, if any.
+func (c *Console) auditDenied(err PermissionError) {
+	if c.PermissionAudit != nil {
+		c.PermissionAudit(err)
+	}
+}
@@ -0,0 +1,105 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestPermissions(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	menu.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		admin := &cobra.Command{Use: "admin", Annotations: map[string]string{CommandRolesKey: "admin,operator"}}
+		wipe := &cobra.Command{
+			Use:         "wipe",
+			Annotations: map[string]string{CommandRolesKey: "admin"},
+			Run:         func(*cobra.Command, []string) {},
+		}
+		status := &cobra.Command{Use: "status", Run: func(*cobra.Command, []string) {}}
+
+		admin.AddCommand(wipe, status)
+		root.AddCommand(admin)
+
+		return root
+	})
+
+	var audited []PermissionError
+	c.PermissionAudit = func(err PermissionError) { audited = append(audited, err) }
+
+	// Without principal, roles are not checked.
+	if _, err := menu.Exec(context.Background(), "admin wipe"); err != nil {
+		t.Fatalf("admin wipe without principal: %v", err)
+	}
+
+	c.SetPrincipal(&Principal{Name: "bob", Roles: []string{"operator"}})
+
+	if _, err := menu.Exec(context.Background(), "admin status"); err != nil {
+		t.Fatalf("admin status as operator: %v", err)
+	}
+
+	res, err := menu.Exec(context.Background(), "admin wipe --help")
+
+	var denied PermissionError
+	if !errors.As(err, &denied) || res.Status != 126 {
+		t.Fatalf("admin wipe as operator = %v (status %d), want a PermissionError", err, res.Status)
+	}
+
+	want := PermissionError{
+		Command:   "admin wipe",
+		Args:      []string{"admin", "wipe", "--help"},
+		Principal: Principal{Name: "bob", Roles: []string{"operator"}},
+		Required:  []string{"admin"},
+	}
+
+	if !reflect.DeepEqual(audited, []PermissionError{want}) {
+		t.Fatalf("audited = %+v, want %+v", audited, want)
+	}
+
+	// Unpermitted commands are hidden, and refused by CheckIsAvailable.
+	menu.resetPreRun()
+
+	wipe, _, _ := menu.Find([]string{"admin", "wipe"})
+	if !wipe.Hidden || !errors.As(menu.CheckIsAvailable(wipe), &denied) {
+		t.Fatal("admin wipe should be hidden and unavailable to an operator")
+	}
+
+	status, _, _ := menu.Find([]string{"admin", "status"})
+	if status.Hidden || menu.CheckIsAvailable(status) != nil {
+		t.Fatal("admin status should be available to an operator")
+	}
+}
+
+func TestPermissionsWithoutGenerator(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	root := &cobra.Command{Use: "root"}
+	wipe := &cobra.Command{
+		Use:         "wipe",
+		Annotations: map[string]string{CommandRolesKey: "admin"},
+		Run:         func(*cobra.Command, []string) {},
+	}
+	root.AddCommand(wipe)
+	menu.Command = root
+
+	c.SetPrincipal(&Principal{Name: "bob", Roles: []string{"operator"}})
+	menu.resetPreRun()
+
+	if !wipe.Hidden {
+		t.Fatal("wipe should be hidden to an operator")
+	}
+
+	// The same tree is shown again to a principal with the role.
+	c.SetPrincipal(&Principal{Name: "alice", Roles: []string{"admin"}})
+	menu.resetPreRun()
+
+	if wipe.Hidden {
+		t.Fatal("wipe still hidden to an admin")
+	}
+}
@@ -0,0 +1,284 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressRefresh = 100 * time.Millisecond // Interval between two redraws of the progress status.
+	progressWidth   = 30                     // Width of progress bars, in cells.
+)
+
+// spinnerFrames are the frames of the spinner shown by progresses without a total.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Progress is a status line, showing the progress of a task as a bar if its
+// total is known, or as a spinner otherwise. It is created with Console.Progress,
+// and is displayed until Done is called. All its methods are safe for concurrent
+// use, and updating it is cheap: the status is redrawn at regular intervals.
+type Progress struct {
+	console *Console
+	mutex   sync.Mutex
+	title   string
+	current int64
+	total   int64
+	start   time.Time
+	done    bool
+}
+
+// Progress creates and displays a new progress status line with a title, which
+// can be updated with Add/Set, and should be removed with Done. If total is not
+// positive, a spinner is shown instead of a progress bar.
+//
+// The status is shown below the running command's output while it runs, and is
+// otherwise shown below the input line (background tasks are thus shown too),
+// with as many lines as there are progresses. When a progress is done, its final
+// status is printed like with Console.TransientPrintf.
+//
+// While a command shows a progress, messages should be printed through the console
+// (TransientPrintf, Writer, NewLogHandler, etc), so that the status is redrawn
+// below them instead of overwriting them.
+func (c *Console) Progress(title string, total int64) *Progress {
+	p := &Progress{
+		console: c,
+		title:   title,
+		total:   total,
+		start:   time.Now(),
+	}
+
+	c.progress.add(c, p)
+
+	return p
+}
+
+// Add adds n to the current progress.
+func (p *Progress) Add(n int64) {
+	p.mutex.Lock()
+	p.current += n
+	p.mutex.Unlock()
+}
+
+// Set sets the current progress.
+func (p *Progress) Set(current int64) {
+	p.mutex.Lock()
+	p.current = current
+	p.mutex.Unlock()
+}
+
+// SetTitle replaces the title of the progress.
+func (p *Progress) SetTitle(title string) {
+	p.mutex.Lock()
+	p.title = title
+	p.mutex.Unlock()
+}
+
+// Write adds the length of b to the current progress, so that the progress
+// of a copy can be shown with io.Copy(io.MultiWriter(dst, progress), src).
+func (p *Progress) Write(b []byte) (int, error) {
+	p.Add(int64(len(b)))
+
+	return len(b), nil
+}
+
+// Done removes the progress from the status, and prints its final status.
+// Calling Done more than once has no effect.
+func (p *Progress) Done() {
+	p.mutex.Lock()
+	done := p.done
+	p.done = true
+	p.mutex.Unlock()
+
+	if !done {
+		p.console.progress.remove(p.console, p)
+	}
+}
+
+// String returns the status line of the progress.
+func (p *Progress) String() string {
+	return p.render(0)
+}
+
+// render returns the status line of the progress, with
+// a given spinner frame if the progress has no total.
+func (p *Progress) render(frame int) string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	elapsed := time.Since(p.start).Round(time.Second)
+
+	if p.total <= 0 {
+		if p.done {
+			return fmt.Sprintf("✓ %s %d (%s)", p.title, p.current, elapsed)
+		}
+
+		return fmt.Sprintf("%s %s %d (%s)", spinnerFrames[frame%len(spinnerFrames)], p.title, p.current, elapsed)
+	}
+
+	ratio := min(max(float64(p.current)/float64(p.total), 0), 1)
+	filled := int(ratio * progressWidth)
+
+	bar := strings.Repeat("=", filled)
+	if filled < progressWidth {
+		bar += ">" + strings.Repeat(" ", progressWidth-filled-1)
+	}
+
+	return fmt.Sprintf("%s [%s] %d/%d (%d%%, %s)", p.title, bar, p.current, p.total, int(ratio*100), elapsed)
+}
+
+// progressDisplay draws the status of all the console progresses,
+// either below the output of a running command, or in the shell hints.
+type progressDisplay struct {
+	mutex     sync.Mutex
+	bars      []*Progress
+	frame     int  // Current spinner frame.
+	lines     int  // Number of status lines printed below the command output.
+	hinted    bool // Whether the status is shown in the shell hints.
+	animating bool // Whether the status is being redrawn at regular intervals.
+}
+
+// add displays a new progress, and starts redrawing the status if needed.
+func (d *progressDisplay) add(c *Console, p *Progress) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.bars = append(d.bars, p)
+	d.draw(c)
+
+	if !d.animating {
+		d.animating = true
+		go d.animate(c)
+	}
+}
+
+// remove stops displaying a progress, and prints its final status.
+func (d *progressDisplay) remove(c *Console, p *Progress) {
+	d.mutex.Lock()
+
+	for i, bar := range d.bars {
+		if bar == p {
+			d.bars = append(d.bars[:i:i], d.bars[i+1:]...)
+			break
+		}
+	}
+
+	final := p.render(0)
+
+	if c.isExecuting.Load() {
+		d.clear()
+		fmt.Println(final)
+		d.draw(c)
+		d.mutex.Unlock()
+
+		return
+	}
+
+	d.draw(c)
+	d.mutex.Unlock()
+
+	_, _ = c.TransientPrintf("%s", final)
+}
+
+// animate redraws the status at regular intervals, until there are no progresses left.
+func (d *progressDisplay) animate(c *Console) {
+	ticker := time.NewTicker(progressRefresh)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		d.mutex.Lock()
+
+		if len(d.bars) == 0 {
+			d.animating = false
+			d.mutex.Unlock()
+
+			return
+		}
+
+		d.frame++
+		d.draw(c)
+		d.mutex.Unlock()
+	}
+}
+
+// draw shows the status of all progresses. It assumes d.mutex is held.
+func (d *progressDisplay) draw(c *Console) {
+	lines := make([]string, 0, len(d.bars))
+	for _, bar := range d.bars {
+		lines = append(lines, bar.render(d.frame))
+	}
+
+	// While a command runs, the shell does not display its hints,
+	// so the status is printed (and redrawn) below the command output.
+	if c.isExecuting.Load() {
+		if d.hinted {
+			c.shell.Hint.ClearTransient()
+			d.hinted = false
+		}
+
+		d.clear()
+
+		if len(lines) > 0 {
+			fmt.Print(strings.Join(lines, "\n"))
+			d.lines = len(lines)
+		}
+
+		return
+	}
+
+	d.lines = 0
+
+	if len(lines) > 0 {
+		c.shell.Hint.SetTransient(strings.Join(lines, "\n"))
+		d.hinted = true
+	} else if d.hinted {
+		c.shell.Hint.ClearTransient()
+		d.hinted = false
+	}
+}
+
+// clear erases the status printed below the command output, leaving
+// the cursor where it was printed. It assumes d.mutex is held.
+func (d *progressDisplay) clear() {
+	if d.lines == 0 {
+		return
+	}
+
+	if d.lines > 1 {
+		fmt.Printf("\x1b[%dA", d.lines-1)
+	}
+
+	fmt.Print("\r\x1b[J")
+
+	d.lines = 0
+}
+
+// printAbove prints command output, above the status if one is shown.
+func (d *progressDisplay) printAbove(c *Console, text string) (n int, err error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	shown := d.lines > 0
+
+	d.clear()
+	n, err = fmt.Print(text)
+
+	if shown {
+		d.draw(c)
+	}
+
+	return n, err
+}
+
+// release erases the status printed below the output of a command
+// which is done, so that it is shown in the shell hints afterwards.
+// The console is marked as not executing while the display is locked,
+// otherwise the status could be redrawn below the output in between.
+func (d *progressDisplay) release(c *Console) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.clear()
+	c.isExecuting.Store(false)
+}
@@ -0,0 +1,68 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	c := New("test")
+	c.isExecuting.Store(true)
+
+	var download, scan *Progress
+
+	out := captureStdout(t, func() {
+		download = c.Progress("download", 200)
+		scan = c.Progress("scan", 0)
+
+		if _, err := io.Copy(download, bytes.NewReader(make([]byte, 50))); err != nil {
+			t.Error(err)
+		}
+
+		c.printLine("host up")
+		scan.Set(3)
+		download.Done()
+		download.Done()
+	})
+
+	if got := download.String(); !strings.HasPrefix(got, "download [=======>      ") ||
+		!strings.Contains(got, "] 50/200 (25%") {
+		t.Fatalf("download status = %q", got)
+	}
+
+	if got := scan.String(); !strings.HasPrefix(got, spinnerFrames[0]+" scan 3 (") {
+		t.Fatalf("scan status = %q", got)
+	}
+
+	// Output is printed above the status, which is redrawn below it.
+	if !strings.Contains(out, "\r\x1b[Jhost up\ndownload [") {
+		t.Fatalf("output not printed above the status: %q", out)
+	}
+
+	// The final status is printed, and the remaining progresses redrawn.
+	final := "\r\x1b[J" + download.String() + "\n"
+	rest := out[strings.LastIndex(out, final)+len(final):]
+
+	if !strings.Contains(out, final) || strings.Contains(rest, "download") || !strings.Contains(rest, " scan 3 (") {
+		t.Fatalf("final status not printed: %q", out)
+	}
+
+	out = captureStdout(t, func() {
+		c.progress.release(c)
+		scan.Done()
+	})
+
+	if !strings.Contains(out, "✓ scan 3 (") {
+		t.Fatalf("final spinner status not printed: %q", out)
+	}
+
+	if c.isExecuting.Load() {
+		t.Fatal("releasing the status should mark the console as not executing")
+	}
+
+	if len(c.progress.bars) != 0 || c.progress.hinted {
+		t.Fatal("done progresses should be removed from the status")
+	}
+}
@@ -1,11 +1,14 @@
 package console
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 
 	"github.com/kballard/go-shellquote"
@@ -34,8 +37,10 @@ func (c *Console) Start() error {
 // Because cobra cannot preempt a running command, a long-running command is
 // only actually interrupted if it observes cancellation itself: select on
 // cmd.Context().Done() (or pass cmd.Context() to context-aware callees) and
-// return promptly. A command that ignores its context keeps running in its
-// goroutine until it finishes, even though the prompt has already been freed.
+// return promptly. The console waits for the interrupted command to return
+// for its GracePeriod, or until a second signal is received: a command still
+// running afterwards is abandoned (see AbandonedError). It keeps running in
+// its goroutine until it finishes, and is listed by Console.Running meanwhile.
 func (c *Console) StartContext(ctx context.Context) error {
 	c.loadActiveHistories()
 
@@ -51,65 +56,175 @@ func (c *Console) StartContext(ctx context.Context) error {
 		// and the last line was not empty.
 		c.displayPostRun(lastLine)
 
-		// Always ensure we work with the active menu, with freshly
-		// generated commands, bound prompts and some other things.
+		lastLine, _ = c.readRun(ctx)
+	}
+}
+
+// RunOnce performs a single iteration of the console loop: it regenerates the
+// active menu, runs the pre-read hooks, reads one line of input with the shell
+// and executes it, blocking until the command returns.
+//
+// Interrupt errors returned by the shell (Ctrl-C, Ctrl-D, etc) are dispatched
+// to the menu interrupt handlers, like signals interrupting the command (which
+// then returns an InterruptedError). Any other error is passed to the menu error
+// handler. In all cases the error is also returned.
+//
+// This is mostly useful to drive the console from tests, or when the caller
+// wishes to run its own loop around it: Start() is otherwise preferred.
+func (c *Console) RunOnce(ctx context.Context) error {
+	_, err := c.readRun(ctx)
+
+	return err
+}
+
+// readRun reads one line of input and runs it,
+// returning the line along with any error.
+func (c *Console) readRun(ctx context.Context) (string, error) {
+	// Always ensure we work with the active menu, with freshly
+	// generated commands, bound prompts and some other things.
+	menu := c.activeMenu()
+	menu.resetPreRun()
+
+	if err := c.runAllE(c.PreReadlineHooks); err != nil {
+		c.setExitStatus(err, nil)
+		err = PreReadError{newError(err, "Pre-read error")}
+		menu.ErrorHandler(err)
+
+		return "", err
+	}
+
+	// Block and read user input.
+	input, err := c.shell.Readline()
+	if errors.Is(err, errSuspend) {
+		c.suspend()
+		return "", nil
+	}
+
+	if err != nil {
+		menu.handleInterrupt(err)
+
+		return input, err
+	}
+
+	// Any call to the SwitchMenu() while we were reading user
+	// input (through an interrupt handler) might have changed it,
+	// so we must be sure we use the good one.
+	menu = c.activeMenu()
+
+	// The user typing the line can answer questions.
+	ctx = context.WithValue(ctx, interactiveKey{}, true)
+
+	// Parse, process and execute the line. Don't check the error
+	// further: if its a cobra error, the library user is responsible
+	// for setting the cobra behavior. If it's an interrupt, we take
+	// care of it: the menu interrupt handler has already been called.
+	if err := c.runLine(ctx, menu, input); err != nil {
+		if !errors.As(err, new(InterruptedError)) {
+			menu.ErrorHandler(err)
+		}
+
+		return input, err
+	}
+
+	return input, nil
+}
+
+// RunScript reads command lines from r and runs them one after the other in
+// the active menu, exactly like they would be if typed at the prompt (comments
+// are removed, line hooks are applied, and menu switches are honored).
+//
+// Execution stops at the first line that fails, or whose command is interrupted
+// by a signal (see InterruptedError): the error is passed to the menu error
+// handler and is returned, so that the caller can exit with a meaningful
+// status, for instance with os.Exit(console.ExitCode(err)).
+// A nil error is returned once r has been entirely consumed.
+func (c *Console) RunScript(ctx context.Context, r io.Reader) error {
+	c.loadActiveHistories()
+
+	// Nobody can answer questions asked by commands.
+	ctx = context.WithValue(ctx, interactiveKey{}, false)
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		input := scanner.Text()
+
+		// Lines ending with an unfinished quote or
+		// escape continue on the next script line.
+		for !line.AcceptMultiline([]rune(input), c.getEscapeMode()) && scanner.Scan() {
+			input += "\n" + scanner.Text()
+		}
+
 		menu := c.activeMenu()
 		menu.resetPreRun()
 
 		if err := c.runAllE(c.PreReadlineHooks); err != nil {
-			menu.ErrorHandler(PreReadError{newError(err, "Pre-read error")})
+			c.setExitStatus(err, nil)
+			err = PreReadError{newError(err, "Pre-read error")}
+			menu.ErrorHandler(err)
 
-			continue
+			return err
 		}
 
-		// Block and read user input.
-		input, err := c.shell.Readline()
-		if err != nil {
-			menu.handleInterrupt(err)
-			lastLine = input
-			continue
+		if err := c.runLine(ctx, menu, input); err != nil {
+			menu.ErrorHandler(err)
+
+			return err
 		}
 
-		// Any call to the SwitchMenu() while we were reading user
-		// input (through an interrupt handler) might have changed it,
-		// so we must be sure we use the good one.
-		menu = c.activeMenu()
+		c.displayPostRun(input)
+	}
 
-		// Parse the line with bash-syntax, removing comments.
-		args, err := line.Parse(input, c.getEscapeMode())
-		if err != nil {
-			menu.ErrorHandler(ParseError{newError(err, "Parsing error")})
-			continue
-		}
+	return scanner.Err()
+}
 
-		if len(args) == 0 {
-			lastLine = input
-			continue
-		}
+// ExitStatus returns the exit status of the last command executed by the
+// console, in the same way a shell would set `$?`: 0 on success, the code
+// of an error implementing ExitCoder, 128 + the signal number if the command
+// was interrupted by a signal, or 1 for any other error.
+func (c *Console) ExitStatus() int {
+	return int(c.status.Load())
+}
 
-		// Run user-provided pre-run line hooks,
-		// which may modify the input line args.
-		args, err = c.runLineHooks(args)
-		if err != nil {
-			menu.ErrorHandler(LineHookError{newError(err, "Line error")})
-			continue
-		}
+// runLine parses an input line, applies the line hooks to it and executes the
+// resulting command in the given menu. The returned error is already wrapped
+// in the type corresponding to the phase during which it occurred, and is an
+// InterruptedError if the command was interrupted by a signal.
+func (c *Console) runLine(ctx context.Context, menu *Menu, input string) error {
+	// Parse the line with bash-syntax, removing comments.
+	args, err := line.Parse(input, c.getEscapeMode())
+	if err != nil {
+		c.setExitStatus(err, nil)
+		return ParseError{newError(err, "Parsing error")}
+	}
 
-		// Print a newline before executing the command if NewlineBefore is true
-		// and the last line was not empty.
-		c.displayPreRun(input)
-
-		// Run all pre-run hooks and the command itself
-		// Don't check the error: if its a cobra error,
-		// the library user is responsible for setting
-		// the cobra behavior.
-		// If it's an interrupt, we take care of it.
-		if err := c.execute(ctx, menu, args, false); err != nil {
-			menu.ErrorHandler(ExecutionError{newError(err, "")})
-		}
+	if len(args) == 0 {
+		return nil
+	}
+
+	// Run user-provided pre-run line hooks,
+	// which may modify the input line args.
+	args, err = c.runLineHooks(args)
+	if err != nil {
+		c.setExitStatus(err, nil)
+		return LineHookError{newError(err, "Line error")}
+	}
+
+	// Print a newline before executing the command if NewlineBefore is true
+	// and the last line was not empty.
+	c.displayPreRun(input)
 
-		lastLine = input
+	// Run all pre-run hooks and the command itself.
+	interrupt, err := c.execute(ctx, menu, args, false)
+	if err == nil && interrupt != nil {
+		err = InterruptedError{Signal: interrupt}
 	}
+
+	if err != nil {
+		return ExecutionError{newError(err, "")}
+	}
+
+	return nil
 }
 
 // RunCommandArgs is a convenience function to run a command line in a given menu.
@@ -124,7 +239,9 @@ func (m *Menu) RunCommandArgs(ctx context.Context, args []string) (err error) {
 	m.resetPreRun()
 
 	// Run the command and associated helpers.
-	return m.console.execute(ctx, m, args, !m.console.isExecuting.Load())
+	_, err = m.console.execute(ctx, m, args, !m.console.isExecuting.Load())
+
+	return err
 }
 
 // RunCommandLine is the equivalent of menu.RunCommandArgs(), but accepts
@@ -135,10 +252,17 @@ func (m *Menu) RunCommandLine(ctx context.Context, input string) (err error) {
 		return
 	}
 
-	// Split the line into shell words, honoring the console's escape mode so
-	// that this path stays consistent with normal interactive execution.
-	var args []string
+	args, err := m.splitLine(input)
+	if err != nil {
+		return err
+	}
 
+	return m.RunCommandArgs(ctx, args)
+}
+
+// splitLine splits a line into shell words, honoring the console's escape mode
+// so that this path stays consistent with normal interactive execution.
+func (m *Menu) splitLine(input string) (args []string, err error) {
 	if m.console.getEscapeMode() == line.EscapeLiteral {
 		args, _, err = line.Split(input, false, line.EscapeLiteral)
 	} else {
@@ -146,10 +270,10 @@ func (m *Menu) RunCommandLine(ctx context.Context, input string) (err error) {
 	}
 
 	if err != nil {
-		return fmt.Errorf("line error: %w", err)
+		return nil, fmt.Errorf("line error: %w", err)
 	}
 
-	return m.RunCommandArgs(ctx, args)
+	return args, nil
 }
 
 // RunMenuCommand runs a processed argument vector against a caller-prepared
@@ -161,7 +285,9 @@ func (m *Menu) RunCommandLine(ctx context.Context, input string) (err error) {
 // RunMenuCommand is for integrations that have already prepared a menu and want
 // to execute against it directly, controlling the async flag themselves.
 func (c *Console) RunMenuCommand(ctx context.Context, menu *Menu, args []string, async bool) error {
-	return c.execute(ctx, menu, args, async)
+	_, err := c.execute(ctx, menu, args, async)
+
+	return err
 }
 
 // execute - The user has entered a command input line, the arguments have been processed:
@@ -170,89 +296,212 @@ func (c *Console) RunMenuCommand(ctx context.Context, menu *Menu, args []string,
 // Our main object of interest is the menu's root command, and we explicitly use this reference
 // instead of the menu itself, because if RunCommand() is asynchronously triggered while another
 // command is running, the menu's root command will be overwritten.
-func (c *Console) execute(ctx context.Context, menu *Menu, args []string, async bool) error {
+// The signal which interrupted the command, if any, is returned along with its error.
+func (c *Console) execute(ctx context.Context, menu *Menu, args []string, async bool) (interrupt os.Signal, err error) {
 	if !async {
 		c.isExecuting.Store(true)
 	}
 
-	defer c.isExecuting.Store(false)
+	// Progresses still running afterwards are shown in the shell hints.
+	defer c.progress.release(c)
+
+	// Whatever the outcome, record it as the last exit status.
+	defer func() { c.setExitStatus(err, interrupt) }()
+
+	// Start monitoring keyboard and OS signals.
+	// signal.Stop releases the channel registration once the command
+	// returns: without it, every command execution would leak a channel
+	// in the os/signal package for the lifetime of the process.
+	sigchan := c.monitorSignals(menu)
+	defer signal.Stop(sigchan)
+
+	interrupt, err = c.run(ctx, &execution{
+		menu:      menu,
+		root:      menu.Command,
+		args:      args,
+		filters:   menu.activeFilters(),
+		principal: c.Principal(),
+		signals:   sigchan,
+	})
 
+	return interrupt, err
+}
+
+// execution gathers everything needed to run a command line
+// against a command tree, either in the console or in a session.
+type execution struct {
+	menu      *Menu          // Menu to which the command tree belongs.
+	root      *cobra.Command // Root of the command tree, used throughout the execution.
+	args      []string       // Processed arguments of the command line.
+	filters   []string       // Active filters, against which the target is checked.
+	principal *Principal     // Principal whose roles are checked against the target (nil if none).
+	signals   chan os.Signal // Signals interrupting the command (nil if none are trapped).
+}
+
+// run executes a command line against its command tree, returning
+// the signal that interrupted the command if any, or its error.
+func (c *Console) run(ctx context.Context, exec *execution) (os.Signal, error) {
 	// Our root command of interest, used throughout this function.
-	cmd := menu.Command
+	cmd := exec.root
 
-	// Find the target command: if this command is filtered, don't run it.
-	target, _, _ := cmd.Find(args)
+	// Cobra only adds its default commands when executing:
+	// add them now, since they must be found like the others.
+	cmd.InitDefaultHelpCmd()
+	cmd.InitDefaultCompletionCmd(exec.args...)
 
-	if err := menu.CheckIsAvailable(target); err != nil {
-		return err
-	}
+	// Find the target command: if there is none, or if
+	// this command is filtered, don't run anything.
+	target, args, err := cmd.Find(exec.args)
 
-	// Restore the target command's flags to their defaults before running it.
-	// When the same command instance is reused (a caller-supplied tree with no
-	// generator), flag values and Changed state from an earlier run would
-	// otherwise leak into this execution.
-	command.ResetFlagsDefaults(target)
+	// The command execution should happen in a separate goroutine,
+	// and should notify the main goroutine when it is done.
+	ctx, cancel := context.WithCancelCause(ctx)
+	run := cmd.Execute
+
+	if handler := exec.menu.notFound(); handler != nil && target == cmd && firstWord(target, args) != "" {
+		run = func() error { return handler(ctx, exec.args) }
+	} else if err := c.checkRunnable(exec, target, args, err); err != nil {
+		cancel(nil)
+		return nil, err
+	} else if err := c.promptMissing(ctx, exec, target, args); err != nil {
+		cancel(nil)
+		return nil, err
+	} else if err := c.confirmRun(ctx, target, args); err != nil {
+		cancel(nil)
+		return nil, err
+	}
 
 	// Console-wide pre-run hooks, cannot.
 	if err := c.runAllE(c.PreCmdRunHooks); err != nil {
-		return fmt.Errorf("pre-run error: %s", err.Error())
+		cancel(nil)
+		return nil, fmt.Errorf("pre-run error: %w", err)
 	}
 
+	// Register the command until it returns, and stop
+	// printing its output if it is ever abandoned.
+	running := c.running.add(exec)
+	ctx = context.WithValue(ctx, runningKey{}, running)
+
 	// Assign those arguments to our parser.
-	cmd.SetArgs(args)
+	cmd.SetArgs(exec.args)
+	cmd.SetContext(ctx)
 
-	// The command execution should happen in a separate goroutine,
-	// and should notify the main goroutine when it is done.
-	ctx, cancel := context.WithCancelCause(ctx)
+	// The streams are restored either when the command returns, or when
+	// it is abandoned if its tree is shared with the next commands.
+	var restored sync.Once
 
-	cmd.SetContext(ctx)
+	restoreIO := command.SetIO(cmd, cmd.InOrStdin(), running.writer(cmd.OutOrStdout()), running.writer(cmd.ErrOrStderr()))
+	restore := func() { restored.Do(restoreIO) }
 
-	// Start monitoring keyboard and OS signals.
-	// signal.Stop releases the channel registration once the command
-	// returns: without it, every command execution would leak a channel
-	// in the os/signal package for the lifetime of the process.
-	sigchan := c.monitorSignals()
-	defer signal.Stop(sigchan)
+	execute := run
+	run = func() (err error) {
+		defer restore()
+		defer waitInitializers(target)()
+		defer recoverPanic("command", &err)
+
+		return execute()
+	}
 
 	// And start the command execution.
-	go c.executeCommand(cmd, cancel)
+	go c.executeCommand(running, run, cancel)
 
-	// Wait for the command to finish, or for an OS signal to be caught.
-	select {
-	case <-ctx.Done():
-		cause := context.Cause(ctx)
+	// Wait for the command to finish, or for an OS signal cancelling it.
+	for {
+		select {
+		case <-ctx.Done():
+			cause := context.Cause(ctx)
 
-		if !errors.Is(cause, context.Canceled) {
-			return cause
-		}
+			if !errors.Is(cause, context.Canceled) {
+				return nil, unknownFlag(target, cause)
+			}
+
+			return nil, nil
+
+		case signal := <-exec.signals:
+			if !c.handleSignal(exec.menu, running, signal) {
+				continue
+			}
+
+			cancel(errors.New(signal.String()))
 
-	case signal := <-sigchan:
-		cancel(errors.New(signal.String()))
+			exec.menu.handleInterrupt(errors.New(signal.String()))
 
-		menu.handleInterrupt(errors.New(signal.String()))
+			err := c.awaitInterrupted(exec.menu, running, signal, exec.signals)
+
+			// Menus without a generator run all their commands with the same
+			// tree: the next ones must not print into the capture of this one.
+			if err != nil && !exec.menu.hasGenerator() {
+				restore()
+			}
+
+			return signal, err
+		}
 	}
+}
 
-	return nil
+// setExitStatus records the exit status of a command, given its error
+// and the signal that interrupted it, if any.
+func (c *Console) setExitStatus(err error, interrupt os.Signal) {
+	if sig, ok := interrupt.(syscall.Signal); ok {
+		c.status.Store(int32(128 + sig))
+		return
+	}
+
+	c.status.Store(int32(ExitCode(err)))
 }
 
 // Run the command in a separate goroutine, and cancel the context when done.
-func (c *Console) executeCommand(cmd *cobra.Command, cancel context.CancelCauseFunc) {
-	if err := cmd.Execute(); err != nil {
-		cancel(err)
+func (c *Console) executeCommand(cmd *runningCommand, run func() error, cancel context.CancelCauseFunc) {
+	cmd.setGoroutine()
 
-		return
-	}
+	err := run()
 
 	// And the post-run hooks in the same goroutine,
 	// because they should not be skipped even if
 	// the command is backgrounded by the user.
-	if err := c.runAllE(c.PostCmdRunHooks); err != nil {
-		cancel(err)
-		return
+	if err == nil {
+		err = c.runAllE(c.PostCmdRunHooks)
+	}
+
+	cmd.returned(err)
+	c.running.remove(cmd)
+
+	// Command executed, cancel the context with its error, if any.
+	cancel(err)
+}
+
+// checkRunnable returns an error if the command line does not resolve to a command,
+// if its target command is filtered or not permitted, and otherwise prepares it for
+// execution. Executions refused for lack of permission are audited.
+func (c *Console) checkRunnable(exec *execution, target *cobra.Command, args []string, findErr error) error {
+	if err := c.unknownCommand(exec, target, args, findErr); err != nil {
+		return err
+	}
+
+	if err := exec.menu.checkAvailable(target, exec.filters); err != nil {
+		return err
+	}
+
+	if err := exec.menu.checkFlagsAvailable(target, args, exec.filters); err != nil {
+		return err
 	}
 
-	// Command successfully executed, cancel the context.
-	cancel(nil)
+	if err := exec.menu.checkPermitted(target, exec.args, exec.principal); err != nil {
+		var denied PermissionError
+		if errors.As(err, &denied) {
+			c.auditDenied(denied)
+		}
+
+		return err
+	}
+
+	// Restore the target command's flags to their defaults before running it.
+	// When the same command instance is reused (a caller-supplied tree with no
+	// generator), flag values and Changed state from an earlier run would
+	// otherwise leak into this execution.
+	command.ResetFlagsDefaults(target)
+
+	return nil
 }
 
 func (c *Console) loadActiveHistories() {
@@ -263,7 +512,9 @@ func (c *Console) loadActiveHistories() {
 	}
 }
 
-func (c *Console) runAllE(hooks []func() error) error {
+func (c *Console) runAllE(hooks []func() error) (err error) {
+	defer recoverPanic("hook", &err)
+
 	for _, hook := range hooks {
 		if err := hook(); err != nil {
 			return err
@@ -273,13 +524,13 @@ func (c *Console) runAllE(hooks []func() error) error {
 	return nil
 }
 
-func (c *Console) runLineHooks(args []string) ([]string, error) {
+func (c *Console) runLineHooks(args []string) (_ []string, err error) {
+	defer recoverPanic("line hook", &err)
+
 	processed := args
 
 	// Or modify them again
 	for _, hook := range c.PreCmdRunLineHooks {
-		var err error
-
 		if processed, err = hook(processed); err != nil {
 			return nil, err
 		}
@@ -314,17 +565,12 @@ var defaultTrapSignals = []os.Signal{
 	syscall.SIGQUIT,
 }
 
-// monitorSignals - Monitor the signals that can be sent to the process
-// while a command is running. We want to be able to cancel the command.
-func (c *Console) monitorSignals() chan os.Signal {
+// monitorSignals - Monitor the signals that can be sent to the process while
+// a command of the menu is running. We want to be able to cancel the command.
+func (c *Console) monitorSignals(menu *Menu) chan os.Signal {
 	sigchan := make(chan os.Signal, 1)
 
-	signals := c.Signals
-	if len(signals) == 0 {
-		signals = defaultTrapSignals
-	}
-
-	signal.Notify(sigchan, signals...)
+	signal.Notify(sigchan, menu.trappedSignals()...)
 
 	return sigchan
 }
@@ -0,0 +1,157 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// exitError is a minimal ExitCoder implementation.
+type exitError struct{ code int }
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+func (e exitError) ExitCode() int { return e.code }
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, 0},
+		{"plain error", errors.New("failed"), 1},
+		{"exit coder", exitError{3}, 3},
+		{"wrapped exit coder", fmt.Errorf("wrapped: %w", exitError{4}), 4},
+		{"execution error", ExecutionError{newError(exitError{5}, "")}, 5},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ExitCode(tc.err); got != tc.want {
+				t.Fatalf("ExitCode(%v) = %d, want %d", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func exitCodeCommands(ran *[]string) Commands {
+	return func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{
+			Use: "ok",
+			Run: func(*cobra.Command, []string) { *ran = append(*ran, "ok") },
+		})
+		root.AddCommand(&cobra.Command{
+			Use:           "fail",
+			SilenceErrors: true,
+			SilenceUsage:  true,
+			RunE: func(*cobra.Command, []string) error {
+				*ran = append(*ran, "fail")
+				return exitError{42}
+			},
+		})
+
+		return root
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	var ran []string
+	menu.SetCommands(exitCodeCommands(&ran))
+
+	err := menu.RunCommandArgs(context.Background(), []string{"fail"})
+	if got := ExitCode(err); got != 42 {
+		t.Fatalf("ExitCode(RunCommandArgs(fail)) = %d, want 42", got)
+	}
+	if got := c.ExitStatus(); got != 42 {
+		t.Fatalf("ExitStatus() after fail = %d, want 42", got)
+	}
+
+	if err := menu.RunCommandArgs(context.Background(), []string{"ok"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ExitStatus(); got != 0 {
+		t.Fatalf("ExitStatus() after ok = %d, want 0", got)
+	}
+}
+
+func TestRunScriptStopsOnError(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	var handled error
+	menu.ErrorHandler = func(err error) error {
+		handled = err
+		return nil
+	}
+
+	var ran []string
+	menu.SetCommands(exitCodeCommands(&ran))
+
+	script := strings.NewReader("# a comment\nok\n\nfail\nok\n")
+
+	err := c.RunScript(context.Background(), script)
+
+	var execErr ExecutionError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("RunScript error = %v, want an ExecutionError", err)
+	}
+	if got := ExitCode(err); got != 42 {
+		t.Fatalf("ExitCode(RunScript) = %d, want 42", got)
+	}
+	if handled == nil {
+		t.Fatal("RunScript did not pass the error to the menu error handler")
+	}
+	if !reflect_equal(ran, []string{"ok", "fail"}) {
+		t.Fatalf("RunScript ran %v, want [ok fail]", ran)
+	}
+}
+
+func TestExitStatusLineErrors(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+	menu.ErrorHandler = func(error) error { return nil }
+
+	var ran []string
+	menu.SetCommands(exitCodeCommands(&ran))
+
+	hookErr := errors.New("refused")
+	c.PreCmdRunLineHooks = append(c.PreCmdRunLineHooks, func(args []string) ([]string, error) {
+		if args[0] == "refuse" {
+			return nil, exitError{3}
+		}
+
+		return args, nil
+	})
+
+	for _, tc := range []struct {
+		hooks  []func() error
+		script string
+		status int
+	}{
+		{nil, "refuse\n", 3},
+		{nil, "ok 'unterminated\n", 1},
+		{[]func() error{func() error { return hookErr }}, "ok\n", 1},
+	} {
+		if err := menu.RunCommandArgs(context.Background(), []string{"ok"}); err != nil || c.ExitStatus() != 0 {
+			t.Fatalf("ok = %v (status %d)", err, c.ExitStatus())
+		}
+
+		c.PreReadlineHooks = tc.hooks
+
+		if err := c.RunScript(context.Background(), strings.NewReader(tc.script)); err == nil {
+			t.Fatalf("%q: RunScript returned no error", tc.script)
+		}
+
+		if got := c.ExitStatus(); got != tc.status {
+			t.Fatalf("%q: ExitStatus() = %d, want %d", tc.script, got, tc.status)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package console
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultGracePeriod is the default value of Console.GracePeriod.
+const defaultGracePeriod = 2 * time.Second
+
+// maxCapturedOutput is the maximum size of the output of an abandoned command
+// kept by the console: only its last bytes are kept.
+const maxCapturedOutput = 64 << 10
+
+// RunningCommand describes a command executed by the console which has
+// not returned yet, either because it is still being waited for, or because
+// it was abandoned (see AbandonedError).
+type RunningCommand struct {
+	ID        int       // Unique identifier of the execution.
+	Menu      string    // Name of the menu in which the command runs.
+	Args      []string  // Command line arguments.
+	Started   time.Time // Time at which the command started.
+	Abandoned bool      // Whether the console stopped waiting for the command.
+	Output    []byte    // Last output printed by the command (through cobra) since it was abandoned.
+	Dropped   int       // Number of bytes of output dropped, to keep the last 64 KiB only.
+
+	goroutine string // Header of the goroutine running the command in stack traces.
+}
+
+// Stack returns the current stack trace of the goroutine running the command,
+// which shows where a stuck command is blocked, or an empty string if the
+// command has returned since.
+func (r RunningCommand) Stack() string {
+	if r.goroutine == "" {
+		return ""
+	}
+
+	buf := make([]byte, 64<<10)
+
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+
+		buf = make([]byte, 2*len(buf))
+	}
+
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		if strings.HasPrefix(stack, r.goroutine) {
+			return stack
+		}
+	}
+
+	return ""
+}
+
+// AbandonedError is returned when the console stops waiting for a command
+// interrupted by a signal, because it has not returned within the grace period
+// (see Console.GracePeriod), or because it was interrupted a second time.
+// The command keeps running in its goroutine, and is listed by Console.Running
+// until it returns: its output is captured instead of being printed (its last
+// 64 KiB only, see RunningCommand.Dropped), and its
+// menu is notified when it returns (see Menu.Notify). Commands of menus without
+// a generator (see Menu.SetCommands) share their tree with the next commands,
+// so their output can't be told apart: it is printed, not captured.
+type AbandonedError struct {
+	ID      int           // Identifier of the command in Console.Running.
+	Command string        // Command line of the command.
+	Signal  os.Signal     // Signal which interrupted the command.
+	Forced  bool          // Whether the command was abandoned on a second signal.
+	Elapsed time.Duration // Time elapsed since the command was interrupted.
+}
+
+// Error implements the error interface.
+func (e AbandonedError) Error() string {
+	reason := fmt.Sprintf("still running %s after %s", e.Elapsed.Round(time.Millisecond), e.Signal)
+	if e.Forced {
+		reason = fmt.Sprintf("interrupted twice by %s", e.Signal)
+	}
+
+	return fmt.Sprintf("command %q (%d) abandoned, %s", e.Command, e.ID, reason)
+}
+
+// ExitCode returns the exit status of a command killed by the signal.
+func (e AbandonedError) ExitCode() int {
+	if sig, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(sig)
+	}
+
+	return 1
+}
+
+// Running returns the commands which have not returned yet, oldest first.
+// This includes the command being executed, if any, and the ones abandoned.
+func (c *Console) Running() []RunningCommand {
+	c.running.mutex.Lock()
+	defer c.running.mutex.Unlock()
+
+	running := make([]RunningCommand, 0, len(c.running.commands))
+
+	for _, cmd := range c.running.commands {
+		cmd.mutex.Lock()
+		info := cmd.info
+		info.Args = append([]string(nil), info.Args...)
+		info.Output = bytes.Clone(cmd.output)
+		cmd.mutex.Unlock()
+
+		running = append(running, info)
+	}
+
+	return running
+}
+
+// commandRegistry holds the commands which have not returned yet.
+type commandRegistry struct {
+	mutex    sync.Mutex
+	lastID   int
+	commands []*runningCommand
+}
+
+// runningCommand is a command registered while it runs.
+type runningCommand struct {
+	mutex     sync.Mutex
+	menu      *Menu
+	info      RunningCommand
+	output    []byte        // Last output written once abandoned.
+	processes []*os.Process // Child processes registered with TrackProcess.
+	done      chan struct{} // Closed when the command returns.
+}
+
+// add registers a new command execution.
+func (r *commandRegistry) add(exec *execution) *runningCommand {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.lastID++
+
+	cmd := &runningCommand{
+		menu: exec.menu,
+		info: RunningCommand{
+			ID:      r.lastID,
+			Menu:    exec.menu.Name(),
+			Args:    exec.args,
+			Started: time.Now(),
+		},
+		done: make(chan struct{}),
+	}
+
+	r.commands = append(r.commands, cmd)
+
+	return cmd
+}
+
+// remove unregisters a command which has returned.
+func (r *commandRegistry) remove(cmd *runningCommand) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i, running := range r.commands {
+		if running == cmd {
+			r.commands = append(r.commands[:i:i], r.commands[i+1:]...)
+			break
+		}
+	}
+
+	close(cmd.done)
+}
+
+// setGoroutine records the goroutine running the command,
+// which must be called from this goroutine.
+func (cmd *runningCommand) setGoroutine() {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	// The stack starts with "goroutine <id> [running]:"
+	if fields := strings.Fields(string(buf)); len(fields) > 1 {
+		cmd.mutex.Lock()
+		cmd.info.goroutine = "goroutine " + fields[1] + " ["
+		cmd.mutex.Unlock()
+	}
+}
+
+// abandon marks the command abandoned, capturing its output from now on.
+func (cmd *runningCommand) abandon() {
+	cmd.mutex.Lock()
+	cmd.info.Abandoned = true
+	cmd.mutex.Unlock()
+}
+
+// returned notifies the menu of the command if it
+// returns after having been abandoned, with its error.
+func (cmd *runningCommand) returned(err error) {
+	cmd.mutex.Lock()
+	abandoned := cmd.info.Abandoned
+	cmd.mutex.Unlock()
+
+	if !abandoned {
+		return
+	}
+
+	msg := fmt.Sprintf("Abandoned command %q (%d) returned", strings.Join(cmd.info.Args, " "), cmd.info.ID)
+
+	if err != nil {
+		cmd.menu.Notify(slog.LevelError, "%s: %s", msg, err)
+	} else {
+		cmd.menu.Notify(slog.LevelWarn, "%s", msg)
+	}
+}
+
+// writer returns a writer printing to w until the command is abandoned.
+func (cmd *runningCommand) writer(w io.Writer) io.Writer {
+	return &commandOutput{cmd: cmd, out: w}
+}
+
+// commandOutput is the output of a command, captured once it is abandoned.
+type commandOutput struct {
+	cmd *runningCommand
+	out io.Writer
+}
+
+// Write implements io.Writer.
+func (o *commandOutput) Write(p []byte) (int, error) {
+	o.cmd.mutex.Lock()
+	defer o.cmd.mutex.Unlock()
+
+	if o.cmd.info.Abandoned {
+		o.cmd.capture(p)
+		return len(p), nil
+	}
+
+	return o.out.Write(p)
+}
+
+// capture appends output of the abandoned command, dropping the oldest bytes
+// beyond maxCapturedOutput. It assumes cmd.mutex is held.
+func (cmd *runningCommand) capture(p []byte) {
+	cmd.output = append(cmd.output, p...)
+
+	if excess := len(cmd.output) - maxCapturedOutput; excess > 0 {
+		cmd.info.Dropped += excess
+		cmd.output = append(cmd.output[:0], cmd.output[excess:]...)
+	}
+}
+
+// awaitInterrupted waits for a command interrupted by a signal to return,
+// for the console grace period at most, or until another signal cancelling
+// it is received. It returns an AbandonedError if the command has not returned.
+func (c *Console) awaitInterrupted(menu *Menu, cmd *runningCommand, sig os.Signal, signals chan os.Signal) error {
+	interrupted := time.Now()
+	forced := false
+
+	select {
+	case <-cmd.done:
+		return nil
+	default:
+	}
+
+	if c.GracePeriod > 0 {
+		timer := time.NewTimer(c.GracePeriod)
+		defer timer.Stop()
+
+	wait:
+		for {
+			select {
+			case <-cmd.done:
+				return nil
+			case next := <-signals:
+				if c.handleSignal(menu, cmd, next) {
+					forced = true
+					break wait
+				}
+			case <-timer.C:
+				break wait
+			}
+		}
+	}
+
+	cmd.abandon()
+
+	return AbandonedError{
+		ID:      cmd.info.ID,
+		Command: strings.Join(cmd.info.Args, " "),
+		Signal:  sig,
+		Forced:  forced,
+		Elapsed: time.Since(interrupted),
+	}
+}
@@ -0,0 +1,41 @@
+package console
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAbandonedOutputLimit(t *testing.T) {
+	c := New("test")
+
+	var printed bytes.Buffer
+
+	cmd := c.running.add(&execution{menu: c.ActiveMenu(), args: []string{"flood"}})
+	out := cmd.writer(&printed)
+
+	_, _ = out.Write([]byte("printed\n"))
+	cmd.abandon()
+
+	line := strings.Repeat("x", 1023) + "\n"
+	for range 100 {
+		_, _ = out.Write([]byte(line))
+	}
+
+	running := c.Running()
+	if len(running) != 1 {
+		t.Fatalf("Running() = %+v, want the abandoned command", running)
+	}
+
+	if printed.String() != "printed\n" {
+		t.Fatalf("printed %q before abandoning the command", printed.String())
+	}
+
+	if got := running[0]; len(got.Output) != maxCapturedOutput || got.Dropped != 100*len(line)-maxCapturedOutput {
+		t.Fatalf("captured %d bytes, dropped %d, want the last %d bytes", len(got.Output), got.Dropped, maxCapturedOutput)
+	}
+
+	if !bytes.HasSuffix(running[0].Output, []byte(line)) {
+		t.Fatal("the last output was not kept")
+	}
+}
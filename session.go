@@ -0,0 +1,348 @@
+package console
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/reeflective/readline"
+
+	"github.com/reeflective/console/internal/command"
+	"github.com/reeflective/console/internal/line"
+)
+
+// Session is a console session attached to a remote client, for instance
+// through a Unix socket or a TCP connection (see Console.Serve).
+//
+// All sessions share the console menus and their command definitions, but
+// each of them has its own active menu, command filters and histories, and
+// executes commands on its own command trees: commands can only be run in
+// sessions in menus with a command generator (see Menu.SetCommands). Commands
+// can access the session in which they run with SessionFromContext(cmd.Context()),
+// for instance to switch its menu: Console.SwitchMenu switches the menu of the
+// console itself, even when called by commands run in sessions. Errors are passed
+// to the menu error handler, the default one printing them to the client.
+//
+// The readline shell only reads from the standard input of the console process
+// and writes to its standard output, so sessions read input in line mode: line
+// editing and history navigation are left to the client, like with
+// `socat READLINE UNIX-CONNECT:<path>` or `rlwrap nc -U <path>`.
+type Session struct {
+	console   *Console
+	conn      io.ReadWriteCloser
+	menu      *Menu
+	filters   []string
+	principal *Principal
+	histories map[string]readline.History
+	status    atomic.Int32
+	mutex     *sync.RWMutex
+}
+
+type sessionKey struct{}
+
+// SessionFromContext returns the session in which a command runs, given its
+// context (cmd.Context()), or nil if it runs in the console itself.
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionKey{}).(*Session)
+
+	return session
+}
+
+// Serve accepts connections on the listener, and runs a new console session
+// for each of them, until the context is canceled (in which case nil is
+// returned) or the listener fails. All sessions are closed before returning.
+//
+// Example, serving a console on a Unix socket in the background:
+//
+//	listener, err := net.Listen("unix", "/run/app/console.sock")
+//	if err != nil {
+//		return err
+//	}
+//
+//	go app.Serve(ctx, listener)
+func (c *Console) Serve(ctx context.Context, listener net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stop := context.AfterFunc(ctx, func() { _ = listener.Close() })
+	defer stop()
+
+	var sessions sync.WaitGroup
+	defer sessions.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		session := c.NewSession(conn)
+
+		sessions.Add(1)
+
+		go func() {
+			defer sessions.Done()
+			_ = session.Run(ctx)
+		}()
+	}
+}
+
+// NewSession returns a new session reading its input from, and writing its
+// output to conn. It starts in the default menu, with the console filters
+// currently active. Use Session.Run to start it.
+func (c *Console) NewSession(conn io.ReadWriteCloser) *Session {
+	return &Session{
+		console:   c,
+		conn:      conn,
+		menu:      c.Menu(""),
+		filters:   c.activeFilters(),
+		histories: make(map[string]readline.History),
+		mutex:     &sync.RWMutex{},
+	}
+}
+
+// Sessions returns all the sessions currently running.
+func (c *Console) Sessions() []*Session {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	sessions := make([]*Session, 0, len(c.sessions))
+	for session := range c.sessions {
+		sessions = append(sessions, session)
+	}
+
+	return sessions
+}
+
+// Run reads and executes command lines from the session connection, until the
+// client disconnects, the session is closed or the context is canceled (all of
+// which return nil). Commands run with a context derived from ctx, canceled
+// if the session terminates while they are running. The connection is closed
+// when Run returns.
+func (s *Session) Run(ctx context.Context) error {
+	c := s.console
+
+	c.mutex.Lock()
+	c.sessions[s] = true
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		delete(c.sessions, s)
+		c.mutex.Unlock()
+	}()
+
+	// Dialogs can't be used, since they are read from the console terminal.
+	ctx = context.WithValue(ctx, interactiveKey{}, false)
+
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, sessionKey{}, s))
+	defer cancel()
+
+	// Closing the connection also interrupts the line being read.
+	defer s.conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { _ = s.conn.Close() })
+	defer stop()
+
+	reader := bufio.NewReader(s.conn)
+
+	for {
+		s.printPrompt()
+
+		input, err := s.readLine(reader)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		s.runLine(ctx, input)
+	}
+}
+
+// Close terminates the session, closing its connection.
+func (s *Session) Close() error {
+	return s.conn.Close()
+}
+
+// Conn returns the connection of the session.
+func (s *Session) Conn() io.ReadWriteCloser {
+	return s.conn
+}
+
+// ActiveMenu returns the active menu of the session.
+func (s *Session) ActiveMenu() *Menu {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.menu
+}
+
+// SwitchMenu switches the active menu of the session, without affecting
+// the console or other sessions. If the menu does not exist, nothing is done.
+func (s *Session) SwitchMenu(menu string) {
+	target := s.console.Menu(menu)
+	if target == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	s.menu = target
+	s.mutex.Unlock()
+}
+
+// HideCommands is like Console.HideCommands, but only for this session.
+func (s *Session) HideCommands(filters ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Running commands might still use the current filters.
+	s.filters = addFilters(slices.Clone(s.filters), filters...)
+}
+
+// ShowCommands is like Console.ShowCommands, but only for this session.
+func (s *Session) ShowCommands(filters ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.filters = removeFilters(s.filters, filters...)
+}
+
+// History returns the history of the session for a given menu,
+// in which all the command lines run in this menu are written.
+func (s *Session) History(menu string) readline.History {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hist, found := s.histories[menu]
+	if !found {
+		hist = readline.NewInMemoryHistory()
+		s.histories[menu] = hist
+	}
+
+	return hist
+}
+
+// ExitStatus returns the exit status of the last command run in the session.
+func (s *Session) ExitStatus() int {
+	return int(s.status.Load())
+}
+
+// Printf prints a message to the session client.
+func (s *Session) Printf(msg string, args ...any) (n int, err error) {
+	return fmt.Fprintf(s.conn, msg, args...)
+}
+
+// runLine parses and executes an input line in the active menu of the session,
+// passing any error to the menu error handler (see Session.error).
+func (s *Session) runLine(ctx context.Context, input string) {
+	c := s.console
+	menu := s.ActiveMenu()
+
+	// Parse the line with bash-syntax, removing comments.
+	args, err := line.Parse(input, c.getEscapeMode())
+	if err != nil {
+		menu.ErrorHandler(ParseError{s.error(err, "Parsing error")})
+		return
+	}
+
+	if len(args) == 0 {
+		return
+	}
+
+	_, _ = s.History(menu.Name()).Write(input)
+
+	// Run user-provided pre-run line hooks,
+	// which may modify the input line args.
+	args, err = c.runLineHooks(args)
+	if err != nil {
+		menu.ErrorHandler(LineHookError{s.error(err, "Line error")})
+		return
+	}
+
+	// Commands run on their own tree, printing to the client.
+	// Menus without a generator have a single command tree,
+	// which can't be executed concurrently with the console.
+	if !menu.hasGenerator() {
+		err = fmt.Errorf("commands of menu %q can't run in sessions without a command generator (see Menu.SetCommands)", menu.Name())
+		menu.ErrorHandler(ExecutionError{s.error(err, "")})
+
+		return
+	}
+
+	s.mutex.RLock()
+	filters := addFilters(slices.Clone(s.filters), menu.ownFilters()...)
+	s.mutex.RUnlock()
+
+	root := menu.newCommands(filters)
+
+	restore := command.SetIO(root, s.conn, s.conn, s.conn)
+	defer restore()
+
+	_, err = c.run(ctx, &execution{
+		menu:      menu,
+		root:      root,
+		args:      args,
+		filters:   filters,
+		principal: s.Principal(),
+	})
+
+	s.status.Store(int32(ExitCode(err)))
+
+	if err != nil {
+		menu.ErrorHandler(ExecutionError{s.error(err, "")})
+	}
+}
+
+// error returns an error of the session, printed
+// to its client by the default menu error handler.
+func (s *Session) error(err error, message string) Err {
+	sessionErr := newError(err, message)
+	sessionErr.out = s.conn
+
+	return sessionErr
+}
+
+// readLine reads an input line from the client, continuing on
+// the next lines as long as the input is not a complete line.
+func (s *Session) readLine(reader *bufio.Reader) (string, error) {
+	var input string
+
+	for {
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+
+		input += strings.TrimRight(text, "\r\n")
+
+		if line.AcceptMultiline([]rune(input), s.console.getEscapeMode()) {
+			return input, nil
+		}
+
+		input += "\n"
+	}
+}
+
+// printPrompt prints the primary prompt of the session active menu.
+func (s *Session) printPrompt() {
+	prompt := s.ActiveMenu().Prompt()
+	if prompt.Primary == nil {
+		return
+	}
+
+	fmt.Fprint(s.conn, prompt.Primary())
+}
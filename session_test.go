@@ -0,0 +1,219 @@
+package console
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// sessionClient is a line-based client of a console session.
+type sessionClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// run sends a command line and returns everything printed
+// by the session until its next prompt (ending with "> ").
+func (cl *sessionClient) run(input string) string {
+	cl.t.Helper()
+
+	if _, err := cl.conn.Write([]byte(input + "\n")); err != nil {
+		cl.t.Fatalf("write %q: %v", input, err)
+	}
+
+	return cl.readPrompt()
+}
+
+func (cl *sessionClient) readPrompt() string {
+	cl.t.Helper()
+
+	_ = cl.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var out strings.Builder
+
+	for !strings.HasSuffix(out.String(), "> ") {
+		char, err := cl.reader.ReadByte()
+		if err != nil {
+			cl.t.Fatalf("read: %v (got %q)", err, out.String())
+		}
+
+		out.WriteByte(char)
+	}
+
+	return out.String()
+}
+
+func newSessionConsole() *Console {
+	c := New("test")
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{
+			Use: "client",
+			Run: func(cmd *cobra.Command, _ []string) {
+				SessionFromContext(cmd.Context()).SwitchMenu("client")
+			},
+		})
+		root.AddCommand(&cobra.Command{
+			Use:         "win",
+			Annotations: map[string]string{CommandFilterKey: "windows"},
+			Run:         func(cmd *cobra.Command, _ []string) { cmd.Println("windows only") },
+		})
+
+		return root
+	})
+
+	c.NewMenu("client").SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{
+			Use: "info",
+			Run: func(cmd *cobra.Command, _ []string) { cmd.Println("client info") },
+		})
+
+		return root
+	})
+
+	return c
+}
+
+func TestServeSessions(t *testing.T) {
+	c := newSessionConsole()
+
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "console.sock"))
+	if err != nil {
+		t.Skipf("cannot listen on a unix socket: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+
+	go func() { served <- c.Serve(ctx, listener) }()
+
+	dial := func() *sessionClient {
+		conn, err := net.Dial("unix", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client := &sessionClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+		client.readPrompt()
+
+		return client
+	}
+
+	first, second := dial(), dial()
+
+	// Each session has its own active menu.
+	if out := first.run("client"); !strings.Contains(out, "[client]") {
+		t.Fatalf("first session prompt after switch = %q, want client menu", out)
+	}
+	if out := first.run("info"); !strings.Contains(out, "client info") {
+		t.Fatalf("first session output = %q, want client info", out)
+	}
+	if out := second.run("info"); !strings.Contains(out, "unknown command") {
+		t.Fatalf("second session output = %q, want an unknown command error", out)
+	}
+	if menu := c.ActiveMenu().Name(); menu != "" {
+		t.Fatalf("console menu = %q, want the default one", menu)
+	}
+
+	// And its own filters.
+	for _, session := range c.Sessions() {
+		if session.ActiveMenu().Name() == "" {
+			session.HideCommands("windows")
+		}
+	}
+
+	if out := second.run("win"); !strings.Contains(out, "hidden by filter expression") {
+		t.Fatalf("filtered command output = %q, want a filter error", out)
+	}
+	menu := c.ActiveMenu()
+	menu.resetPreRun()
+
+	win, _, _ := menu.Find([]string{"win"})
+	if err := menu.CheckIsAvailable(win); err != nil || win.Hidden {
+		t.Fatalf("session filters leaked to the console: %v", err)
+	}
+
+	// Canceling the context terminates all sessions.
+	cancel()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("Serve returned %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after its context was canceled")
+	}
+
+	if sessions := c.Sessions(); len(sessions) != 0 {
+		t.Fatalf("%d sessions still running", len(sessions))
+	}
+}
+
+func TestSessionWithoutGenerator(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	var out strings.Builder
+
+	root := &cobra.Command{Use: "root"}
+	root.AddCommand(&cobra.Command{Use: "echo", Run: func(cmd *cobra.Command, _ []string) {}})
+	root.SetOut(&out)
+	menu.Command = root
+
+	server, conn := net.Pipe()
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() { _ = c.NewSession(server).Run(ctx) }()
+
+	client := &sessionClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+	client.readPrompt()
+
+	// The menu tree is not shared with sessions.
+	if got := client.run("echo"); !strings.Contains(got, "without a command generator") {
+		t.Fatalf("session output = %q, want a generator error", got)
+	}
+
+	if root.OutOrStdout() != &out {
+		t.Fatal("session changed the output of the menu commands")
+	}
+}
+
+// recordedConn is a connection recording whether it was closed.
+type recordedConn struct {
+	io.Reader
+	io.Writer
+	closed bool
+}
+
+func (c *recordedConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSessionClosedOnEOF(t *testing.T) {
+	c := New("test")
+
+	conn := &recordedConn{Reader: strings.NewReader("help\n"), Writer: io.Discard}
+
+	if err := c.NewSession(conn).Run(context.Background()); err != nil {
+		t.Fatalf("Run returned %v, want nil on EOF", err)
+	}
+
+	if !conn.closed {
+		t.Fatal("the session connection was not closed after the client disconnected")
+	}
+}
@@ -0,0 +1,153 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+)
+
+// SignalAction is what a menu does when the console traps one of its
+// signals while one of its commands runs (see Menu.SetSignalAction).
+type SignalAction int
+
+const (
+	// SignalCancel cancels the command context, runs the menu interrupt
+	// handlers and waits for the command to return (see Console.GracePeriod).
+	// This is the action of trapped signals without another one.
+	SignalCancel SignalAction = iota
+
+	// SignalIgnore discards the signal: the command keeps running.
+	SignalIgnore
+
+	// SignalForward sends the signal to the child processes of the command
+	// (see TrackProcess), while the command itself keeps running.
+	SignalForward
+)
+
+// errSuspend is returned by the shell when the user asks to suspend the console.
+var errSuspend = errors.New("suspended")
+
+// runningKey is the context key of the command running with a context.
+type runningKey struct{}
+
+// signalHandler is the action taken by a menu for a signal.
+type signalHandler struct {
+	action  SignalAction
+	handler func(c *Console, sig os.Signal) // Custom handler, overriding the action if not nil.
+}
+
+// SetSignals sets the OS signals trapped while the commands of the menu run,
+// instead of the console ones (see Console.Signals). Without arguments, the
+// console signals are trapped again.
+func (m *Menu) SetSignals(signals ...os.Signal) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.signals = append([]os.Signal(nil), signals...)
+}
+
+// SetSignalAction sets what the menu does when a signal is trapped while one
+// of its commands runs, instead of cancelling it (see SignalAction). A signal
+// with an action is trapped even if it is not one of the menu signals.
+func (m *Menu) SetSignalAction(sig os.Signal, action SignalAction) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.signalHandlers[sig] = signalHandler{action: action}
+}
+
+// HandleSignal is like SetSignalAction, but the handler is called when the
+// signal is trapped while a command of the menu runs, and the command keeps
+// running. A nil handler restores the default action (cancelling the command).
+func (m *Menu) HandleSignal(sig os.Signal, handler func(c *Console, sig os.Signal)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if handler == nil {
+		delete(m.signalHandlers, sig)
+		return
+	}
+
+	m.signalHandlers[sig] = signalHandler{handler: handler}
+}
+
+// TrackProcess registers a child process started by the command running with
+// ctx (its cmd.Context()): the signals for which its menu has the SignalForward
+// action are sent to the process, as long as the command runs. For instance:
+//
+//	child := exec.CommandContext(cmd.Context(), "tail", "-f", "/var/log/syslog")
+//	if err := child.Start(); err != nil {
+//		return err
+//	}
+//	console.TrackProcess(cmd.Context(), child.Process)
+//
+// It does nothing if ctx is not the context of a command run by the console.
+func TrackProcess(ctx context.Context, proc *os.Process) {
+	cmd, ok := ctx.Value(runningKey{}).(*runningCommand)
+	if !ok || proc == nil {
+		return
+	}
+
+	cmd.mutex.Lock()
+	cmd.processes = append(cmd.processes, proc)
+	cmd.mutex.Unlock()
+}
+
+// trappedSignals returns the signals trapped while the commands
+// of the menu run: its own, or the console ones, or the default
+// ones, along with all the signals for which it has an action.
+func (m *Menu) trappedSignals() []os.Signal {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	signals := m.signals
+	if len(signals) == 0 {
+		signals = m.console.Signals
+	}
+
+	if len(signals) == 0 {
+		signals = defaultTrapSignals
+	}
+
+	signals = append([]os.Signal(nil), signals...)
+
+	for sig := range m.signalHandlers {
+		if !slices.Contains(signals, sig) {
+			signals = append(signals, sig)
+		}
+	}
+
+	return signals
+}
+
+// handleSignal takes the action of the menu for a signal trapped while
+// a command runs, and returns true if the command should be cancelled.
+func (c *Console) handleSignal(menu *Menu, cmd *runningCommand, sig os.Signal) (cancel bool) {
+	menu.mutex.RLock()
+	handler := menu.signalHandlers[sig]
+	menu.mutex.RUnlock()
+
+	switch {
+	case handler.handler != nil:
+		handler.handler(c, sig)
+	case handler.action == SignalIgnore:
+	case handler.action == SignalForward:
+		cmd.forward(sig)
+	default:
+		return true
+	}
+
+	return false
+}
+
+// forward sends a signal to the child processes of the command.
+func (cmd *runningCommand) forward(sig os.Signal) {
+	cmd.mutex.Lock()
+	processes := append([]*os.Process(nil), cmd.processes...)
+	cmd.mutex.Unlock()
+
+	for _, proc := range processes {
+		_ = proc.Signal(sig)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package console
+
+// bindSuspend does nothing: suspending the console is only supported on unix.
+func (c *Console) bindSuspend() {}
+
+// suspend does nothing: suspending the console is only supported on unix.
+func (c *Console) suspend() {}
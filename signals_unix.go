@@ -0,0 +1,47 @@
+//go:build unix
+
+package console
+
+import (
+	"syscall"
+
+	"github.com/reeflective/readline/inputrc"
+)
+
+// suspendCommand is the name of the shell command suspending the console.
+const suspendCommand = "suspend-console"
+
+// bindSuspend binds Ctrl-Z to suspend the console while it reads input, like the
+// terminal does while commands run: the shell reads input in raw mode, in which
+// Ctrl-Z does not send SIGTSTP. Keymaps in which Ctrl-Z is already bound to
+// another command than self-insert are left untouched.
+func (c *Console) bindSuspend() {
+	c.shell.Keymap.Register(map[string]func(){
+		suspendCommand: func() {
+			// Return from the shell, which restores the terminal,
+			// and keep the line for when the console is continued.
+			c.shell.Display.AcceptLine()
+			c.shell.History.Accept(true, false, errSuspend)
+		},
+	})
+
+	ctrlZ := inputrc.Unescape(`\C-z`)
+
+	for _, keymap := range []string{"emacs", "emacs-standard", "vi-insert", "vi-command", "vi-move"} {
+		binds := c.shell.Config.Binds[keymap]
+		if binds == nil {
+			continue
+		}
+
+		if bind, bound := binds[ctrlZ]; !bound || bind.Action == "self-insert" {
+			binds[ctrlZ] = inputrc.Bind{Action: suspendCommand}
+		}
+	}
+}
+
+// suspend stops the console process group with SIGTSTP, like Ctrl-Z does while a
+// command runs, until the parent shell continues it (SIGCONT). The terminal has
+// been restored by the console shell, which sets it up again when reading input.
+func (c *Console) suspend() {
+	_ = syscall.Kill(0, syscall.SIGTSTP)
+}
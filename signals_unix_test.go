@@ -3,11 +3,19 @@
 package console
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/exec"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/spf13/cobra"
 )
 
 // TestMonitorSignalsCustom verifies that monitorSignals honors a customized
@@ -17,7 +25,7 @@ func TestMonitorSignalsCustom(t *testing.T) {
 	c := New("test")
 	c.Signals = []os.Signal{syscall.SIGUSR1}
 
-	ch := c.monitorSignals()
+	ch := c.monitorSignals(c.ActiveMenu())
 	defer signal.Stop(ch)
 
 	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
@@ -33,3 +41,341 @@ func TestMonitorSignalsCustom(t *testing.T) {
 		t.Fatal("timed out waiting for the custom signal")
 	}
 }
+
+// runInterrupted runs a command in the active menu, sending it the
+// given number of SIGUSR1 signals once the command has started.
+func runInterrupted(t *testing.T, c *Console, run func(cmd *cobra.Command, started chan struct{}), signals int) error {
+	t.Helper()
+
+	c.Signals = []os.Signal{syscall.SIGUSR1}
+	started := make(chan struct{})
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{
+			Use: "work",
+			Run: func(cmd *cobra.Command, _ []string) { run(cmd, started) },
+		})
+
+		return root
+	})
+
+	go func() {
+		<-started
+
+		for range signals {
+			if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+				t.Errorf("failed to raise SIGUSR1: %v", err)
+			}
+
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	return c.ActiveMenu().RunCommandArgs(context.Background(), []string{"work"})
+}
+
+func TestInterruptedCommandReturns(t *testing.T) {
+	c := New("test")
+
+	err := runInterrupted(t, c, func(cmd *cobra.Command, started chan struct{}) {
+		close(started)
+		<-cmd.Context().Done()
+	}, 1)
+
+	if err != nil {
+		t.Fatalf("command returning within the grace period: %v", err)
+	}
+
+	if running := c.Running(); len(running) != 0 {
+		t.Fatalf("running commands = %+v, want none", running)
+	}
+}
+
+func TestInterruptedScript(t *testing.T) {
+	c := New("test")
+	c.Signals = []os.Signal{syscall.SIGUSR1}
+	c.ActiveMenu().ErrorHandler = func(error) error { return nil }
+
+	var ran []string
+
+	started := make(chan struct{})
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{Use: "root"}
+		root.AddCommand(&cobra.Command{
+			Use: "work",
+			Run: func(cmd *cobra.Command, _ []string) {
+				ran = append(ran, "work")
+				close(started)
+				<-cmd.Context().Done()
+			},
+		}, &cobra.Command{
+			Use: "next",
+			Run: func(*cobra.Command, []string) { ran = append(ran, "next") },
+		})
+
+		return root
+	})
+
+	go func() {
+		<-started
+
+		if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+			t.Errorf("failed to raise SIGUSR1: %v", err)
+		}
+	}()
+
+	err := c.RunScript(context.Background(), strings.NewReader("work\nnext\n"))
+
+	var interrupted InterruptedError
+	if !errors.As(err, &interrupted) || interrupted.Signal != syscall.SIGUSR1 {
+		t.Fatalf("RunScript error = %v, want the command interrupted by SIGUSR1", err)
+	}
+
+	if code := ExitCode(err); code != 128+int(syscall.SIGUSR1) || c.ExitStatus() != code {
+		t.Fatalf("exit code = %d (status %d), want %d", code, c.ExitStatus(), 128+int(syscall.SIGUSR1))
+	}
+
+	if !slices.Equal(ran, []string{"work"}) {
+		t.Fatalf("ran %v, want the script to stop after the interrupted command", ran)
+	}
+}
+
+func TestInterruptedCommandAbandoned(t *testing.T) {
+	c := New("test")
+	c.GracePeriod = 50 * time.Millisecond
+
+	abandon := make(chan struct{})
+	printed := make(chan struct{})
+	release := make(chan struct{})
+
+	err := runInterrupted(t, c, func(cmd *cobra.Command, started chan struct{}) {
+		close(started)
+		<-abandon
+		cmd.Println("late output")
+		close(printed)
+		<-release
+	}, 1)
+
+	var abandoned AbandonedError
+	if !errors.As(err, &abandoned) || abandoned.Forced || abandoned.Signal != syscall.SIGUSR1 {
+		t.Fatalf("error = %v, want a command abandoned after the grace period", err)
+	}
+
+	if status := c.ExitStatus(); status != 128+int(syscall.SIGUSR1) {
+		t.Fatalf("exit status = %d, want %d", status, 128+int(syscall.SIGUSR1))
+	}
+
+	close(abandon)
+	<-printed
+
+	running := c.Running()
+	if len(running) != 1 || running[0].ID != abandoned.ID || !running[0].Abandoned ||
+		!slices.Equal(running[0].Args, []string{"work"}) {
+		t.Fatalf("running commands = %+v, want the abandoned one", running)
+	}
+
+	if string(running[0].Output) != "late output\n" {
+		t.Fatalf("output of the abandoned command = %q, want it captured", running[0].Output)
+	}
+
+	if stack := running[0].Stack(); !strings.Contains(stack, "TestInterruptedCommandAbandoned") {
+		t.Fatalf("stack of the abandoned command:\n%s", stack)
+	}
+
+	// Once it returns, the command is unregistered, and its menu notified.
+	c.NewMenu("other")
+	c.SwitchMenu("other")
+	close(release)
+
+	for deadline := time.Now().Add(5 * time.Second); len(c.Running()) > 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("the abandoned command is still registered after returning")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	messages := c.Menu("").Messages()
+	if len(messages) != 1 || messages[0].Text != `Abandoned command "work" (1) returned` {
+		t.Fatalf("menu messages = %+v, want the command return", messages)
+	}
+}
+
+func TestAbandonedCommandWithoutGenerator(t *testing.T) {
+	c := New("test")
+	c.GracePeriod = 50 * time.Millisecond
+	c.Signals = []os.Signal{syscall.SIGUSR1}
+
+	release := make(chan struct{})
+	defer close(release)
+
+	started := make(chan struct{})
+
+	var buf strings.Builder
+
+	menu := c.ActiveMenu()
+	menu.SetOut(&buf)
+	menu.AddCommand(&cobra.Command{
+		Use: "work",
+		Run: func(*cobra.Command, []string) {
+			close(started)
+			<-release
+		},
+	}, &cobra.Command{
+		Use: "echo",
+		Run: func(cmd *cobra.Command, args []string) { cmd.Println(strings.Join(args, " ")) },
+	})
+
+	go func() {
+		<-started
+
+		if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+			t.Errorf("failed to raise SIGUSR1: %v", err)
+		}
+	}()
+
+	var abandoned AbandonedError
+	if err := menu.RunCommandArgs(context.Background(), []string{"work"}); !errors.As(err, &abandoned) {
+		t.Fatalf("error = %v, want an abandoned command", err)
+	}
+
+	<-started // The tree was initialized by the abandoned execution.
+
+	if err := menu.RunCommandArgs(context.Background(), []string{"echo", "hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "hello\n" {
+		t.Fatalf("menu output = %q, want the output of the next command", buf.String())
+	}
+
+	if running := c.Running(); len(running) != 1 || len(running[0].Output) != 0 {
+		t.Fatalf("running commands = %+v, want the abandoned one without output", running)
+	}
+}
+
+func TestInterruptedCommandForced(t *testing.T) {
+	c := New("test")
+	c.GracePeriod = time.Minute
+
+	release := make(chan struct{})
+	defer close(release)
+
+	start := time.Now()
+
+	err := runInterrupted(t, c, func(_ *cobra.Command, started chan struct{}) {
+		close(started)
+		<-release
+	}, 2)
+
+	var abandoned AbandonedError
+	if !errors.As(err, &abandoned) || !abandoned.Forced {
+		t.Fatalf("error = %v, want a command abandoned on the second signal", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("forced return took %s", elapsed)
+	}
+}
+
+func TestMenuSignals(t *testing.T) {
+	c := New("test")
+	menu := c.ActiveMenu()
+
+	if got := menu.trappedSignals(); !slices.Equal(got, c.Signals) {
+		t.Fatalf("trapped signals = %v, want the console ones", got)
+	}
+
+	menu.SetSignals(syscall.SIGUSR2)
+	menu.HandleSignal(syscall.SIGHUP, func(*Console, os.Signal) {})
+
+	if got := menu.trappedSignals(); !slices.Equal(got, []os.Signal{syscall.SIGUSR2, syscall.SIGHUP}) {
+		t.Fatalf("trapped signals = %v, want [SIGUSR2 SIGHUP]", got)
+	}
+
+	menu.SetSignals()
+	menu.HandleSignal(syscall.SIGHUP, nil)
+
+	if got := menu.trappedSignals(); !slices.Equal(got, c.Signals) {
+		t.Fatalf("trapped signals = %v, want the console ones again", got)
+	}
+}
+
+func TestSignalActions(t *testing.T) {
+	c := New("test")
+	c.ActiveMenu().SetSignalAction(syscall.SIGUSR1, SignalIgnore)
+
+	err := runInterrupted(t, c, func(cmd *cobra.Command, started chan struct{}) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+
+		if cmd.Context().Err() != nil {
+			t.Error("ignored signal cancelled the command")
+		}
+	}, 2)
+	if err != nil {
+		t.Fatalf("command with an ignored signal: %v", err)
+	}
+
+	// Custom handlers.
+	var handled []os.Signal
+
+	c.ActiveMenu().HandleSignal(syscall.SIGUSR1, func(_ *Console, sig os.Signal) {
+		handled = append(handled, sig)
+	})
+
+	err = runInterrupted(t, c, func(_ *cobra.Command, started chan struct{}) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+	}, 1)
+	if err != nil || !slices.Equal(handled, []os.Signal{syscall.SIGUSR1}) {
+		t.Fatalf("command with a handled signal: %v, handled %v", err, handled)
+	}
+
+	// Signals forwarded to child processes.
+	c.ActiveMenu().SetSignalAction(syscall.SIGUSR1, SignalForward)
+
+	err = runInterrupted(t, c, func(cmd *cobra.Command, started chan struct{}) {
+		child := exec.Command("sleep", "10")
+		if err := child.Start(); err != nil {
+			t.Error(err)
+			close(started)
+
+			return
+		}
+
+		TrackProcess(cmd.Context(), child.Process)
+		close(started)
+
+		var exitErr *exec.ExitError
+		if err := child.Wait(); !errors.As(err, &exitErr) || cmd.Context().Err() != nil {
+			t.Errorf("child process error = %v, want it killed by the forwarded signal", err)
+		}
+	}, 1)
+	if err != nil {
+		t.Fatalf("command with a forwarded signal: %v", err)
+	}
+}
+
+func TestSuspendBinding(t *testing.T) {
+	c := New("test")
+
+	if bind := c.Shell().Config.Binds["emacs"][inputrc.Unescape(`\C-z`)]; bind.Action != suspendCommand {
+		t.Fatalf("Ctrl-Z is bound to %q, want %q", bind.Action, suspendCommand)
+	}
+
+	// Ctrl-Z returns from the shell, keeping the line for the next read.
+	c.Shell().Keys.Feed(false, []rune("ls\x1a")...)
+
+	if _, err := c.Shell().Readline(); !errors.Is(err, errSuspend) {
+		t.Fatalf("Ctrl-Z error = %v, want errSuspend", err)
+	}
+
+	c.Shell().Keys.Feed(false, '\r')
+
+	if line, err := c.Shell().Readline(); err != nil || line != "ls" {
+		t.Fatalf("line after Ctrl-Z = %q, %v, want the held line", line, err)
+	}
+}
@@ -0,0 +1,310 @@
+package console
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/reeflective/console/internal/strutil"
+)
+
+// maxSuggestions is the maximum number of suggestions for an unknown command or flag.
+const maxSuggestions = 5
+
+type (
+	// Suggestion is a command or a flag close to an unknown one.
+	Suggestion struct {
+		Name     string // Command path (without the menu root), or flag name (with dashes).
+		Alias    string // Alias of the command close to the unknown word, if not its name.
+		Menu     string // Name of the menu in which the command is.
+		Distance int    // Edit distance between the unknown word and the suggestion.
+	}
+
+	// UnknownCommandError is returned when a command line does not resolve to a command,
+	// either because its first word is not a command of the menu, or because a word is
+	// not a subcommand of a command that is not runnable by itself. It holds suggestions
+	// of commands close to the unknown word, from the menu but also from other menus.
+	UnknownCommandError struct {
+		Name        string       // The unknown command word.
+		Parent      string       // Path of the command in which the word was searched ("" for the menu root).
+		Menu        string       // Name of the menu in which the command was searched.
+		Suggestions []Suggestion // Commands close to the unknown one, best first.
+	}
+
+	// UnknownFlagError is returned when a command is given a flag it does not
+	// have. It holds suggestions of the command flags close to the unknown one.
+	UnknownFlagError struct {
+		Flag        string       // The unknown flag, as typed.
+		Command     string       // Path of the command to which the flag was given.
+		Suggestions []Suggestion // Flags close to the unknown one, best first.
+		err         error
+	}
+)
+
+// Error implements the error interface.
+func (e UnknownCommandError) Error() string {
+	msg := fmt.Sprintf("unknown command %q", e.Name)
+	if e.Parent != "" {
+		msg += fmt.Sprintf(" for %q", e.Parent)
+	}
+
+	return msg + e.suggestions()
+}
+
+func (e UnknownCommandError) suggestions() string {
+	if len(e.Suggestions) == 0 {
+		return ""
+	}
+
+	var msg strings.Builder
+
+	msg.WriteString("\n\nDid you mean this?\n")
+
+	for _, suggestion := range e.Suggestions {
+		fmt.Fprintf(&msg, "\t%s", suggestion.Name)
+
+		if suggestion.Alias != "" {
+			fmt.Fprintf(&msg, " (alias %s)", suggestion.Alias)
+		}
+
+		if suggestion.Menu != e.Menu {
+			fmt.Fprintf(&msg, " (menu %q)", suggestion.Menu)
+		}
+
+		msg.WriteString("\n")
+	}
+
+	return strings.TrimSuffix(msg.String(), "\n")
+}
+
+// Error implements the error interface.
+func (e UnknownFlagError) Error() string {
+	msg := e.err.Error()
+
+	if len(e.Suggestions) > 0 {
+		msg += "\n\nDid you mean this?\n"
+
+		for _, suggestion := range e.Suggestions {
+			msg += "\t" + suggestion.Name + "\n"
+		}
+	}
+
+	return strings.TrimSuffix(msg, "\n")
+}
+
+// Unwrap returns the flag parsing error.
+func (e UnknownFlagError) Unwrap() error {
+	return e.err
+}
+
+// unknownCommand returns an UnknownCommandError if the command line does not
+// resolve to a command in the execution tree, given the results of its Find.
+func (c *Console) unknownCommand(exec *execution, target *cobra.Command, args []string, findErr error) error {
+	root := exec.root
+
+	// Cobra only fails to find commands at the root: deeper, the
+	// unknown word would be passed to a non-runnable parent.
+	unknown := findErr != nil || (target != root && !target.Runnable() && target.HasAvailableSubCommands())
+	if !unknown {
+		return nil
+	}
+
+	// Cobra shell completion requests are handled by cobra itself.
+	name := firstWord(target, args)
+	if name == "" || name == cobra.ShellCompRequestCmd || name == cobra.ShellCompNoDescRequestCmd {
+		return nil
+	}
+
+	err := UnknownCommandError{
+		Name: name,
+		Menu: exec.menu.name,
+	}
+
+	if target != root {
+		err.Parent = commandPath(target)
+	}
+
+	err.Suggestions = suggestCommands(name, exec.menu.name, target)
+
+	// Commands at the root of other menus, with their own filters.
+	if target == root {
+		for _, menu := range c.Menus() {
+			if menu == exec.menu {
+				continue
+			}
+
+			tree := menu.newCommands(menu.activeFilters())
+			err.Suggestions = append(err.Suggestions, suggestCommands(name, menu.name, tree)...)
+		}
+	}
+
+	err.Suggestions = sortSuggestions(err.Suggestions)
+
+	return err
+}
+
+// unknownFlag returns an UnknownFlagError if err is an unknown flag
+// error from the target command, or err itself otherwise.
+func unknownFlag(target *cobra.Command, err error) error {
+	var notExist *pflag.NotExistError
+	if target == nil || !errors.As(err, &notExist) {
+		return err
+	}
+
+	flagErr := UnknownFlagError{
+		Command: commandPath(target),
+		err:     err,
+	}
+
+	// Suggestions are only given for long flags.
+	if shorthands := notExist.GetSpecifiedShortnames(); shorthands != "" {
+		flagErr.Flag = "-" + shorthands
+		return flagErr
+	}
+
+	name := notExist.GetSpecifiedName()
+	flagErr.Flag = "--" + name
+
+	var suggestions []Suggestion
+
+	visit := func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+
+		if distance, ok := closeTo(name, flag.Name); ok {
+			suggestions = append(suggestions, Suggestion{Name: "--" + flag.Name, Distance: distance})
+		}
+	}
+
+	target.Flags().VisitAll(visit)
+	target.InheritedFlags().VisitAll(visit)
+
+	flagErr.Suggestions = sortSuggestions(suggestions)
+
+	return flagErr
+}
+
+// suggestCommands returns the subcommands of parent (or their aliases) close to name.
+func suggestCommands(name, menu string, parent *cobra.Command) []Suggestion {
+	var suggestions []Suggestion
+
+	for _, cmd := range parent.Commands() {
+		if !cmd.IsAvailableCommand() {
+			continue
+		}
+
+		suggestion := Suggestion{Name: commandPath(cmd), Menu: menu, Distance: -1}
+
+		if distance, ok := closeTo(name, cmd.Name()); ok {
+			suggestion.Distance = distance
+		}
+
+		for _, alias := range cmd.Aliases {
+			distance, ok := closeTo(name, alias)
+			if ok && (suggestion.Distance < 0 || distance < suggestion.Distance) {
+				suggestion.Distance = distance
+				suggestion.Alias = alias
+			}
+		}
+
+		if suggestion.Distance >= 0 {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	return suggestions
+}
+
+// closeTo returns the edit distance between word and candidate, and true if
+// the candidate is close enough to be suggested, or if it starts with word.
+func closeTo(word, candidate string) (int, bool) {
+	word, candidate = strings.ToLower(word), strings.ToLower(candidate)
+	distance := strutil.Distance(word, candidate)
+
+	if distance <= min(2, max(1, len(word)/2)) {
+		return distance, true
+	}
+
+	if len(word) > 1 && strings.HasPrefix(candidate, word) {
+		return distance, true
+	}
+
+	return distance, false
+}
+
+func sortSuggestions(suggestions []Suggestion) []Suggestion {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Distance < suggestions[j].Distance
+	})
+
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+
+	return suggestions
+}
+
+// firstWord returns the first argument given to cmd that is neither a flag
+// nor a flag value, like cobra does when searching for subcommands.
+func firstWord(cmd *cobra.Command, args []string) string {
+	local, inherited := cmd.LocalFlags(), cmd.InheritedFlags()
+
+	lookup := func(name string) *pflag.Flag {
+		if flag := local.Lookup(name); flag != nil {
+			return flag
+		}
+
+		return inherited.Lookup(name)
+	}
+
+	shorthandLookup := func(name string) *pflag.Flag {
+		if flag := local.ShorthandLookup(name); flag != nil {
+			return flag
+		}
+
+		return inherited.ShorthandLookup(name)
+	}
+
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+
+		switch {
+		case arg == "--":
+			return ""
+		case strings.HasPrefix(arg, "--") && !strings.Contains(arg, "=") && takesValue(lookup(arg[2:])):
+			args = skipValue(args)
+		case strings.HasPrefix(arg, "-") && len(arg) == 2 && takesValue(shorthandLookup(arg[1:])):
+			args = skipValue(args)
+		case arg != "" && !strings.HasPrefix(arg, "-"):
+			return arg
+		}
+	}
+
+	return ""
+}
+
+// takesValue returns true if a flag, when given without "=", uses the next argument
+// as its value. Unknown flags are assumed to, as cobra does.
+func takesValue(flag *pflag.Flag) bool {
+	return flag == nil || flag.NoOptDefVal == ""
+}
+
+// skipValue removes a flag value from the remaining arguments, unless it is the last one.
+func skipValue(args []string) []string {
+	if len(args) <= 1 {
+		return nil
+	}
+
+	return args[1:]
+}
+
+// commandPath returns the path of a command, without its (usually anonymous) menu root.
+func commandPath(cmd *cobra.Command) string {
+	return strings.TrimSpace(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()))
+}
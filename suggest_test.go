@@ -0,0 +1,150 @@
+package console
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func newSuggestConsole() *Console {
+	c := New("test")
+	run := func(*cobra.Command, []string) {}
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.PersistentFlags().String("format", "", "")
+
+		connect := &cobra.Command{Use: "connect", Aliases: []string{"dial"}, Run: run, SilenceErrors: true, SilenceUsage: true}
+		connect.Flags().String("proto", "", "")
+		connect.Flags().Bool("proxy", false, "")
+		connect.Flags().Bool("hidden", false, "")
+		_ = connect.Flags().MarkHidden("hidden")
+
+		config := &cobra.Command{Use: "config"}
+		config.AddCommand(&cobra.Command{Use: "show", Run: run}, &cobra.Command{Use: "set", Run: run})
+
+		root.AddCommand(connect, config, &cobra.Command{Use: "secret", Hidden: true, Run: run})
+
+		return root
+	})
+
+	c.NewMenu("client").SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddCommand(&cobra.Command{Use: "download", Run: run}, &cobra.Command{Use: "connect", Run: run})
+
+		return root
+	})
+
+	return c
+}
+
+func TestUnknownCommandSuggestions(t *testing.T) {
+	c := newSuggestConsole()
+	menu := c.ActiveMenu()
+
+	tests := []struct {
+		line   string
+		parent string
+		want   []Suggestion
+	}{
+		{"conect", "", []Suggestion{
+			{Name: "connect", Distance: 1},
+			{Name: "connect", Menu: "client", Distance: 1},
+		}},
+		{"dail host", "", []Suggestion{{Name: "connect", Alias: "dial", Distance: 2}}},
+		{"downlaod", "", []Suggestion{{Name: "download", Menu: "client", Distance: 2}}},
+		{"secre", "", nil},
+		{"config shwo", "config", []Suggestion{{Name: "config show", Distance: 2}}},
+		{"nothing", "", nil},
+	}
+
+	for _, tc := range tests {
+		err := menu.RunCommandLine(context.Background(), tc.line)
+
+		var unknown UnknownCommandError
+		if !errors.As(err, &unknown) {
+			t.Errorf("%q: error = %v, want an UnknownCommandError", tc.line, err)
+			continue
+		}
+
+		if unknown.Parent != tc.parent || !reflect.DeepEqual(unknown.Suggestions, tc.want) {
+			t.Errorf("%q: parent %q, suggestions %+v, want %q, %+v", tc.line, unknown.Parent, unknown.Suggestions, tc.parent, tc.want)
+		}
+	}
+
+	err := menu.RunCommandLine(context.Background(), "conect")
+	if msg := err.Error(); !strings.Contains(msg, "Did you mean this?\n\tconnect\n\tconnect (menu \"client\")") {
+		t.Fatalf("error message = %q, want suggestions", msg)
+	}
+
+	// Known and builtin commands still run.
+	for _, line := range []string{"connect", "config show", "help"} {
+		if _, err := menu.Exec(context.Background(), line); err != nil {
+			t.Errorf("%q: unexpected error: %v", line, err)
+		}
+	}
+}
+
+func TestUnknownFlagSuggestions(t *testing.T) {
+	c := newSuggestConsole()
+	menu := c.ActiveMenu()
+
+	err := menu.RunCommandLine(context.Background(), "connect --prot tcp")
+
+	var unknown UnknownFlagError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("error = %v, want an UnknownFlagError", err)
+	}
+
+	want := []Suggestion{{Name: "--proto", Distance: 1}, {Name: "--proxy", Distance: 2}}
+	if unknown.Flag != "--prot" || unknown.Command != "connect" || !reflect.DeepEqual(unknown.Suggestions, want) {
+		t.Fatalf("unknown flag error = %+v, want --prot for connect with %+v", unknown, want)
+	}
+
+	err = menu.RunCommandLine(context.Background(), "connect --hidde")
+	if errors.As(err, &unknown) && len(unknown.Suggestions) != 0 {
+		t.Fatalf("hidden flags suggested: %+v", unknown.Suggestions)
+	}
+}
+
+func TestSuggestionsMenuFilters(t *testing.T) {
+	c := New("test")
+	run := func(*cobra.Command, []string) {}
+
+	c.ActiveMenu().SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddCommand(&cobra.Command{Use: "noop", Run: run})
+
+		return root
+	})
+
+	client := c.NewMenu("client")
+	client.SetCommands(func() *cobra.Command {
+		root := &cobra.Command{}
+		root.AddCommand(&cobra.Command{
+			Use:         "download",
+			Annotations: map[string]string{CommandFilterKey: "remote"},
+			Run:         run,
+		})
+
+		return root
+	})
+
+	// The filters of a menu don't apply to the others.
+	c.ActiveMenu().HideCommands("remote")
+
+	var unknown UnknownCommandError
+	if err := c.ActiveMenu().RunCommandLine(context.Background(), "downlaod"); !errors.As(err, &unknown) || len(unknown.Suggestions) != 1 {
+		t.Fatalf("error = %v, want the download command of the client menu suggested", err)
+	}
+
+	client.HideCommands("remote")
+
+	if err := c.ActiveMenu().RunCommandLine(context.Background(), "downlaod"); !errors.As(err, &unknown) || len(unknown.Suggestions) != 0 {
+		t.Fatalf("error = %v, want no suggestion for a command filtered in its menu", err)
+	}
+}
@@ -0,0 +1,83 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// Writer returns a writer printing what is written to it with the console,
+// line by line: partial lines are buffered until they are complete. Like log
+// handlers (see NewLogHandler), lines are printed above the prompt while the
+// console is reading input, and on plain stdout while a command is running.
+//
+// This is meant for third-party libraries (HTTP servers, SDK loggers, etc)
+// that only accept a writer, and would otherwise corrupt the input line.
+// The writer is safe for concurrent use. A partial line left when the writer
+// is no longer used is only printed when it is closed, so callers must close
+// it once done with it.
+func (c *Console) Writer() io.WriteCloser {
+	return &lineWriter{print: c.printLine}
+}
+
+// Writer returns a writer printing lines like Console.Writer does, but only
+// while the menu is the active one: lines written while another menu is active
+// are queued as messages, replayed when the menu becomes active again.
+// Like the console one, it must be closed to print a trailing partial line.
+func (m *Menu) Writer() io.WriteCloser {
+	return &lineWriter{print: func(line string) {
+		m.Notify(slog.LevelInfo, "%s", line)
+	}}
+}
+
+// printLine prints a line above the prompt, or on stdout while a command runs.
+func (c *Console) printLine(line string) {
+	if c.isExecuting.Load() {
+		_, _ = c.progress.printAbove(c, line+"\n")
+	} else {
+		_, _ = c.TransientPrintf("%s", line)
+	}
+}
+
+// lineWriter buffers partial lines, and prints complete ones.
+type lineWriter struct {
+	mutex sync.Mutex
+	buf   []byte
+	print func(line string)
+}
+
+// Write implements io.Writer.
+func (w *lineWriter) Write(data []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.buf = append(w.buf, data...)
+
+	for {
+		end := bytes.IndexByte(w.buf, '\n')
+		if end == -1 {
+			break
+		}
+
+		line := bytes.TrimSuffix(w.buf[:end], []byte("\r"))
+		w.print(string(line))
+
+		w.buf = w.buf[end+1:]
+	}
+
+	return len(data), nil
+}
+
+// Close prints the pending partial line, if any. The writer can still be used.
+func (w *lineWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if len(w.buf) > 0 {
+		w.print(string(bytes.TrimSuffix(w.buf, []byte("\r"))))
+		w.buf = nil
+	}
+
+	return nil
+}
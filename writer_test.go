@@ -0,0 +1,42 @@
+package console
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWriters(t *testing.T) {
+	c := New("test")
+	c.isExecuting.Store(true)
+
+	client := c.NewMenu("client")
+
+	out := captureStdout(t, func() {
+		w := c.Writer()
+		fmt.Fprint(w, "partial ")
+		fmt.Fprint(w, "line\r\nsecond")
+		fmt.Fprint(w, " line\nlast")
+
+		// The last partial line is printed on close.
+		if err := w.Close(); err != nil {
+			t.Error(err)
+		}
+
+		// Lines of inactive menus are queued.
+		fmt.Fprintln(client.Writer(), "held")
+	})
+
+	if want := "partial line\nsecond line\nlast\n"; out != want {
+		t.Fatalf("console writer printed %q, want %q", out, want)
+	}
+
+	out = captureStdout(t, func() {
+		c.SwitchMenu("client")
+		fmt.Fprintln(client.Writer(), "direct")
+	})
+
+	if !strings.HasSuffix(out, " held\ndirect\n") {
+		t.Fatalf("menu writer printed %q, want the queued line replayed", out)
+	}
+}